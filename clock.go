@@ -0,0 +1,177 @@
+// ©Hayabusa Cloud Co., Ltd. 2025. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+//go:build linux
+
+package iofd
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unsafe"
+
+	"code.hybscloud.com/zcall"
+)
+
+// Now returns the current time of clockid in nanoseconds, e.g. a value
+// suitable for TimerFD.ArmAt.
+//
+// CLOCK_REALTIME and CLOCK_MONOTONIC are read through time.Now without
+// entering the kernel. CLOCK_MONOTONIC is derived from the monotonic
+// reading of time.Now and a clock_gettime(2) value taken once: it may lag
+// the clock by the short interval between the two, but never runs ahead of
+// it. Other clocks cost a clock_gettime(2) system call on each call. Inside
+// a time namespace, the values include the namespace's offsets, as all
+// clocks seen by the process do.
+func Now(clockid int) (int64, error) {
+	switch clockid {
+	case CLOCK_REALTIME:
+		return time.Now().UnixNano(), nil
+	case CLOCK_MONOTONIC:
+		base, err := readMonotonicBase()
+		if err != nil {
+			return 0, err
+		}
+		// time.Since uses the monotonic clock reading of base.at
+		return base.ns + int64(time.Since(base.at)), nil
+	}
+	return clockGettime(clockid)
+}
+
+// monotonicBase pairs a CLOCK_MONOTONIC value with a time.Now taken just
+// after it, so that later readings of time.Now convert to the clock.
+type monotonicBase struct {
+	ns int64
+	at time.Time
+}
+
+var readMonotonicBase = sync.OnceValues(func() (monotonicBase, error) {
+	ns, err := clockGettime(CLOCK_MONOTONIC)
+	if err != nil {
+		return monotonicBase{}, err
+	}
+	return monotonicBase{ns: ns, at: time.Now()}, nil
+})
+
+// ClockResolution returns the resolution of clockid as reported by
+// clock_getres(2).
+func ClockResolution(clockid int) (time.Duration, error) {
+	var ts timespec
+	_, errno := zcall.Syscall4(SYS_CLOCK_GETRES, uintptr(clockid), uintptr(unsafe.Pointer(&ts)), 0, 0)
+	if errno != 0 {
+		return 0, errFromErrno(errno)
+	}
+	return time.Duration(ts.sec*1e9 + ts.nsec), nil
+}
+
+// clockGettime calls clock_gettime(2) and returns the time in nanoseconds.
+func clockGettime(clockid int) (int64, error) {
+	var ts timespec
+	_, errno := zcall.Syscall4(SYS_CLOCK_GETTIME, uintptr(clockid), uintptr(unsafe.Pointer(&ts)), 0, 0)
+	if errno != 0 {
+		return 0, errFromErrno(errno)
+	}
+	return ts.sec*1e9 + ts.nsec, nil
+}
+
+// TimensOffsets are the offsets of a time namespace: the time by which its
+// CLOCK_MONOTONIC and CLOCK_BOOTTIME are ahead of the initial namespace.
+// Other clocks are not virtualized.
+//
+// Deadlines computed with Now inside the namespace need no adjustment. The
+// offsets convert absolute times that cross a namespace boundary, such as a
+// deadline received from the host or from a process in another namespace.
+type TimensOffsets struct {
+	Monotonic time.Duration
+	Boottime  time.Duration
+}
+
+// ReadTimensOffsets returns the time namespace offsets of the calling
+// process from /proc/self/timens_offsets. Without time namespace support
+// in the kernel, all offsets are zero.
+func ReadTimensOffsets() (TimensOffsets, error) {
+	return readTimensOffsets("/proc/self/timens_offsets")
+}
+
+// TimensOffsets returns the time namespace offsets of the process.
+// Returns ErrProcessExited if it has been reaped.
+func (p *PidFD) TimensOffsets() (TimensOffsets, error) {
+	raw := p.fd.Raw()
+	if raw < 0 {
+		return TimensOffsets{}, ErrClosed
+	}
+	o, err := readTimensOffsets("/proc/" + strconv.Itoa(p.pid) + "/timens_offsets")
+	if err != nil {
+		return TimensOffsets{}, p.procErr(raw, err)
+	}
+	// The entry may have described another process reusing the PID
+	if err := p.checkAlive(raw); err != nil {
+		return TimensOffsets{}, err
+	}
+	return o, nil
+}
+
+func readTimensOffsets(path string) (TimensOffsets, error) {
+	b, err := readFile(path)
+	if err == zcall.ENOENT {
+		return TimensOffsets{}, nil
+	}
+	if err != nil {
+		return TimensOffsets{}, err
+	}
+	return parseTimensOffsets(string(b))
+}
+
+// parseTimensOffsets parses lines of "<clock> <sec> <nsec>", where clock is
+// a name or a numeric clock ID.
+func parseTimensOffsets(s string) (TimensOffsets, error) {
+	var o TimensOffsets
+	for line := range strings.Lines(s) {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 3 {
+			return TimensOffsets{}, ErrInvalidParam
+		}
+		sec, err1 := strconv.ParseInt(fields[1], 10, 64)
+		nsec, err2 := strconv.ParseInt(fields[2], 10, 64)
+		if err1 != nil || err2 != nil {
+			return TimensOffsets{}, ErrInvalidParam
+		}
+		d := time.Duration(sec*1e9 + nsec)
+		switch fields[0] {
+		case "monotonic", strconv.Itoa(CLOCK_MONOTONIC):
+			o.Monotonic = d
+		case "boottime", strconv.Itoa(CLOCK_BOOTTIME):
+			o.Boottime = d
+		}
+	}
+	return o, nil
+}
+
+// offset returns the offset applying to clockid.
+func (o TimensOffsets) offset(clockid int) int64 {
+	switch clockid {
+	case CLOCK_MONOTONIC:
+		return int64(o.Monotonic)
+	case CLOCK_BOOTTIME, CLOCK_BOOTTIME_ALARM:
+		return int64(o.Boottime)
+	}
+	return 0
+}
+
+// ToHost converts an absolute time of clockid in nanoseconds from the
+// namespace to the initial time namespace.
+func (o TimensOffsets) ToHost(clockid int, ns int64) int64 {
+	return ns - o.offset(clockid)
+}
+
+// FromHost converts an absolute time of clockid in nanoseconds from the
+// initial time namespace to the namespace.
+func (o TimensOffsets) FromHost(clockid int, ns int64) int64 {
+	return ns + o.offset(clockid)
+}
@@ -0,0 +1,68 @@
+// ©Hayabusa Cloud Co., Ltd. 2025. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+//go:build linux
+
+package iofd
+
+import "code.hybscloud.com/iox"
+
+// clockWatchDeadline is the latest absolute time accepted by
+// timerfd_settime(2), so the watcher's timer never expires.
+const clockWatchDeadline = ((1<<63-1)/1e9 - 1) * 1e9
+
+// ClockChangeWatcher reports discontinuous changes of the realtime clock,
+// such as a step by NTP or an operator, or a resume from suspend.
+//
+// It is a CLOCK_REALTIME timerfd armed with ArmAtCancelOnSet for a
+// deadline that is never reached: its descriptor becomes readable only
+// when the clock is set. Call Changed whenever it becomes readable.
+type ClockChangeWatcher struct {
+	t *TimerFD
+}
+
+// NewClockChangeWatcher creates a ClockChangeWatcher.
+func NewClockChangeWatcher() (*ClockChangeWatcher, error) {
+	t, err := NewTimerFDRealtime()
+	if err != nil {
+		return nil, err
+	}
+	if err := t.ArmAtCancelOnSet(clockWatchDeadline, 0); err != nil {
+		_ = t.Close()
+		return nil, err
+	}
+	return &ClockChangeWatcher{t: t}, nil
+}
+
+// Fd returns the underlying timerfd.
+// Implements PollFd interface.
+func (w *ClockChangeWatcher) Fd() int {
+	return w.t.Fd()
+}
+
+// Close closes the timerfd.
+// Implements PollCloser interface.
+func (w *ClockChangeWatcher) Close() error {
+	return w.t.Close()
+}
+
+// Changed reports whether the realtime clock has been set since the last
+// call, and resets the notification. Several changes between two calls
+// are reported once.
+func (w *ClockChangeWatcher) Changed() (bool, error) {
+	_, err := w.t.Read()
+	switch err {
+	case ErrClockChanged:
+		return true, nil
+	case nil, iox.ErrWouldBlock:
+		return false, nil
+	}
+	return false, err
+}
+
+// Compile-time interface assertions
+var (
+	_ PollFd     = (*ClockChangeWatcher)(nil)
+	_ PollCloser = (*ClockChangeWatcher)(nil)
+)
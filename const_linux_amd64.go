@@ -8,10 +8,37 @@ package iofd
 
 // Syscall numbers for Linux amd64.
 const (
-	SYS_DUP       = 32
-	SYS_DUP2      = 33
-	SYS_DUP3      = 292
-	SYS_FCNTL     = 72
-	SYS_FTRUNCATE = 77
-	SYS_FSTAT     = 5
+	SYS_DUP               = 32
+	SYS_DUP2              = 33
+	SYS_DUP3              = 292
+	SYS_FCNTL             = 72
+	SYS_FTRUNCATE         = 77
+	SYS_FSTAT             = 5
+	SYS_IOCTL             = 16
+	SYS_OPENAT            = 257
+	SYS_SETNS             = 308
+	SYS_PROCESS_VM_READV  = 310
+	SYS_PROCESS_VM_WRITEV = 311
+	SYS_PROCESS_MADVISE   = 440
+	SYS_GETDENTS64        = 217
+	SYS_READLINKAT        = 267
+	SYS_WAITID            = 247
+	SYS_PRCTL             = 157
+	SYS_EPOLL_CREATE1     = 291
+	SYS_EPOLL_CTL         = 233
+	SYS_EPOLL_PWAIT       = 281
+	SYS_EPOLL_PWAIT2      = 441
+	SYS_PPOLL             = 271
+	SYS_PREADV2           = 327
+	SYS_RT_SIGPROCMASK    = 14
+	SYS_GETTID            = 186
+	SYS_RT_SIGACTION      = 13
+	SYS_TGKILL            = 234
+	SYS_TIMER_CREATE      = 222
+	SYS_TIMER_SETTIME     = 223
+	SYS_TIMER_GETTIME     = 224
+	SYS_TIMER_GETOVERRUN  = 225
+	SYS_TIMER_DELETE      = 226
+	SYS_CLOCK_GETTIME     = 228
+	SYS_CLOCK_GETRES      = 229
 )
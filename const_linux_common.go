@@ -15,6 +15,7 @@ const (
 // File status flags for fcntl F_GETFL/F_SETFL.
 // These are consistent across all Linux architectures.
 const (
+	O_RDONLY   = 0x0
 	O_NONBLOCK = 0x800
 	O_CLOEXEC  = 0x80000
 )
@@ -8,10 +8,37 @@ package iofd
 
 // Syscall numbers for Linux loong64 (uses generic syscall table).
 const (
-	SYS_DUP       = 23
-	SYS_DUP2      = 0 // Not available; use fcntl F_DUPFD
-	SYS_DUP3      = 24
-	SYS_FCNTL     = 25
-	SYS_FTRUNCATE = 46
-	SYS_FSTAT     = 80
+	SYS_DUP               = 23
+	SYS_DUP2              = 0 // Not available; use fcntl F_DUPFD
+	SYS_DUP3              = 24
+	SYS_FCNTL             = 25
+	SYS_FTRUNCATE         = 46
+	SYS_FSTAT             = 80
+	SYS_IOCTL             = 29
+	SYS_OPENAT            = 56
+	SYS_SETNS             = 268
+	SYS_PROCESS_VM_READV  = 270
+	SYS_PROCESS_VM_WRITEV = 271
+	SYS_PROCESS_MADVISE   = 440
+	SYS_GETDENTS64        = 61
+	SYS_READLINKAT        = 78
+	SYS_WAITID            = 95
+	SYS_PRCTL             = 167
+	SYS_EPOLL_CREATE1     = 20
+	SYS_EPOLL_CTL         = 21
+	SYS_EPOLL_PWAIT       = 22
+	SYS_EPOLL_PWAIT2      = 441
+	SYS_PPOLL             = 73
+	SYS_PREADV2           = 286
+	SYS_RT_SIGPROCMASK    = 135
+	SYS_GETTID            = 178
+	SYS_RT_SIGACTION      = 134
+	SYS_TGKILL            = 131
+	SYS_TIMER_CREATE      = 107
+	SYS_TIMER_SETTIME     = 110
+	SYS_TIMER_GETTIME     = 108
+	SYS_TIMER_GETOVERRUN  = 109
+	SYS_TIMER_DELETE      = 111
+	SYS_CLOCK_GETTIME     = 113
+	SYS_CLOCK_GETRES      = 114
 )
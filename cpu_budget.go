@@ -0,0 +1,236 @@
+// ©Hayabusa Cloud Co., Ltd. 2025. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+//go:build linux
+
+package iofd
+
+import (
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CPUBudget notifies when a thread or process has consumed a given amount
+// of CPU time.
+//
+// timerfd rejects CPU clocks, so the budget is enforced with a
+// CLOCK_MONOTONIC TimerFD instead: a CPU clock advances at most as fast as
+// wall time multiplied by the number of CPUs it can run on, so the timer is
+// armed for the shortest wall time in which the remaining budget could be
+// consumed. When it expires, Check samples the CPU clock and either reports
+// exhaustion or rearms for the new remainder. The notification is therefore
+// never late, but may come early and require several checks for a mostly
+// idle consumer.
+//
+// Fd returns the timerfd: call Check whenever it becomes readable.
+type CPUBudget struct {
+	mu      sync.Mutex
+	timer   *TimerFD
+	clockid int
+	rate    int64  // Maximum CPU nanoseconds per wall-clock nanosecond
+	pidfd   *PidFD // Process measured by a PidFD budget, not owned
+	budget  int64
+	start   int64
+}
+
+// cpuBudgetMinStep bounds the rearm interval so that a nearly exhausted
+// budget of an idle consumer does not cause a storm of wakeups.
+const cpuBudgetMinStep = 500 * time.Microsecond
+
+// NewThreadCPUBudget creates a CPUBudget for the CPU time of the calling OS
+// thread, as measured by CLOCK_THREAD_CPUTIME_ID, starting now.
+//
+// The budget follows the thread, not the goroutine: the goroutine to be
+// measured should hold runtime.LockOSThread. Check may be called from any
+// thread.
+func NewThreadCPUBudget(budget time.Duration) (*CPUBudget, error) {
+	return newCPUBudget(threadCPUClock(gettid()), 1, nil, budget)
+}
+
+// NewProcessCPUBudget creates a CPUBudget for the CPU time of all threads
+// of the calling process, as measured by CLOCK_PROCESS_CPUTIME_ID,
+// starting now.
+func NewProcessCPUBudget(budget time.Duration) (*CPUBudget, error) {
+	return newCPUBudget(CLOCK_PROCESS_CPUTIME_ID, onlineCPUs(), nil, budget)
+}
+
+// NewPidFDCPUBudget creates a CPUBudget for the CPU time of all threads of
+// the process referred to by p, starting now. p must stay open while the
+// budget is used. Once the process has exited, Check and Used return
+// ErrProcessExited, since its numeric PID may be reused.
+func NewPidFDCPUBudget(p *PidFD, budget time.Duration) (*CPUBudget, error) {
+	if p.fd.Raw() < 0 {
+		return nil, ErrClosed
+	}
+	return newCPUBudget(processCPUClock(p.pid), onlineCPUs(), p, budget)
+}
+
+func newCPUBudget(clockid int, rate int64, p *PidFD, budget time.Duration) (*CPUBudget, error) {
+	if budget <= 0 {
+		return nil, ErrInvalidParam
+	}
+	timer, err := NewTimerFD()
+	if err != nil {
+		return nil, err
+	}
+	b := &CPUBudget{timer: timer, clockid: clockid, rate: rate, pidfd: p}
+	if err := b.Reset(budget); err != nil {
+		_ = timer.Close()
+		return nil, err
+	}
+	return b, nil
+}
+
+// Fd returns the underlying timerfd.
+// Implements PollFd interface.
+func (b *CPUBudget) Fd() int {
+	return b.timer.Fd()
+}
+
+// Close closes the timerfd. A PidFD passed to NewPidFDCPUBudget stays open.
+// Implements PollCloser interface.
+func (b *CPUBudget) Close() error {
+	return b.timer.Close()
+}
+
+// Reset restarts accounting from the current CPU time with a new budget.
+func (b *CPUBudget) Reset(budget time.Duration) error {
+	if budget <= 0 {
+		return ErrInvalidParam
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now, err := b.sample()
+	if err != nil {
+		return err
+	}
+	b.budget = int64(budget)
+	b.start = now
+	return b.arm(b.budget)
+}
+
+// Budget returns the CPU time allowed since the last Reset.
+func (b *CPUBudget) Budget() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return time.Duration(b.budget)
+}
+
+// Used returns the CPU time consumed since the last Reset.
+func (b *CPUBudget) Used() (time.Duration, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now, err := b.sample()
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(now - b.start), nil
+}
+
+// Check consumes the pending timer notification and reports whether the
+// budget is exhausted. While it is not, the timer is rearmed for the
+// remaining budget; once it is, the timer stays disarmed until Reset.
+func (b *CPUBudget) Check() (bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.timer.fd.Raw() < 0 {
+		return false, ErrClosed
+	}
+	// Drain the expiration count; iox.ErrWouldBlock only means Check was
+	// called before the timer expired
+	_, _ = b.timer.Read()
+	now, err := b.sample()
+	if err != nil {
+		return false, err
+	}
+	remaining := b.budget - (now - b.start)
+	if remaining <= 0 {
+		return true, b.timer.Disarm()
+	}
+	return false, b.arm(remaining)
+}
+
+// arm schedules the next check for the shortest wall time in which
+// remaining CPU nanoseconds could be consumed.
+func (b *CPUBudget) arm(remaining int64) error {
+	wait := remaining / b.rate
+	if wait < int64(cpuBudgetMinStep) {
+		wait = int64(cpuBudgetMinStep)
+	}
+	return b.timer.Arm(wait, 0)
+}
+
+// sample reads the CPU clock, verifying afterwards that a process measured
+// through a PidFD has not exited.
+func (b *CPUBudget) sample() (int64, error) {
+	if b.timer.fd.Raw() < 0 {
+		return 0, ErrClosed
+	}
+	now, err := clockGettime(b.clockid)
+	if b.pidfd == nil {
+		return now, err
+	}
+	raw := b.pidfd.fd.Raw()
+	if raw < 0 {
+		return 0, ErrClosed
+	}
+	// The pidfd becomes readable when the process exits
+	fds := [1]PollEntry{{Fd: raw, Events: POLLIN}}
+	var zero timespec
+	if n, perr := ppoll(fds[:], &zero, nil); perr == nil && n > 0 {
+		return 0, ErrProcessExited
+	}
+	return now, err
+}
+
+// processCPUClock returns the clock ID of the CPU clock of process pid, as
+// clock_getcpuclockid(3) does. threadCPUClock returns that of thread tid,
+// as pthread_getcpuclockid(3) does, which unlike CLOCK_THREAD_CPUTIME_ID
+// can be read from other threads.
+func processCPUClock(pid int) int {
+	return (^pid)<<3 | cpuClockSched
+}
+
+func threadCPUClock(tid int) int {
+	return (^tid)<<3 | cpuClockSched | cpuClockPerThread
+}
+
+// Encoding of CPU clock IDs in the kernel
+const (
+	cpuClockSched     = 2
+	cpuClockPerThread = 4
+)
+
+// onlineCPUs returns the number of online CPUs, an upper bound for the
+// rate at which a process clock advances.
+var onlineCPUs = sync.OnceValue(func() int64 {
+	b, err := readFile("/sys/devices/system/cpu/online")
+	if err != nil {
+		return int64(runtime.NumCPU())
+	}
+	var n int64
+	for r := range strings.SplitSeq(strings.TrimSpace(string(b)), ",") {
+		lo, hi, isRange := strings.Cut(r, "-")
+		first, err1 := strconv.Atoi(lo)
+		last := first
+		var err2 error
+		if isRange {
+			last, err2 = strconv.Atoi(hi)
+		}
+		if err1 != nil || err2 != nil || last < first {
+			return int64(runtime.NumCPU())
+		}
+		n += int64(last - first + 1)
+	}
+	return max(n, int64(runtime.NumCPU()))
+})
+
+// Compile-time interface assertions
+var (
+	_ PollFd     = (*CPUBudget)(nil)
+	_ PollCloser = (*CPUBudget)(nil)
+)
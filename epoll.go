@@ -0,0 +1,251 @@
+// ©Hayabusa Cloud Co., Ltd. 2025. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+//go:build linux
+
+package iofd
+
+import (
+	"math"
+	"sync/atomic"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"code.hybscloud.com/zcall"
+)
+
+// Epoll represents a Linux epoll instance.
+//
+// Descriptors are registered with a 64-bit user data value that is
+// returned with their events, so callers can map events back to handles
+// without a lookup by descriptor number. An Epoll is itself a PollFd,
+// readable while events are pending, so epolls can be nested.
+type Epoll struct {
+	fd FD
+}
+
+// NewEpoll creates a new epoll instance with EPOLL_CLOEXEC.
+func NewEpoll() (*Epoll, error) {
+	fd, err := epollCreate()
+	if err != nil {
+		return nil, err
+	}
+	return &Epoll{fd: fd}, nil
+}
+
+// Fd returns the underlying file descriptor.
+// Implements PollFd interface.
+func (ep *Epoll) Fd() int {
+	return ep.fd.Fd()
+}
+
+// Close closes the epoll instance. Registered descriptors stay open.
+// Implements PollCloser interface.
+func (ep *Epoll) Close() error {
+	return ep.fd.Close()
+}
+
+// Add registers fd for events, e.g. EPOLLIN | EPOLLET, returning data with
+// each of its events. Returns zcall.EEXIST if fd is already registered.
+func (ep *Epoll) Add(fd PollFd, events uint32, data uint64) error {
+	return ep.ctl(EPOLL_CTL_ADD, fd, events, data)
+}
+
+// Modify changes the events and data of a registered fd. It also rearms
+// a descriptor registered with EPOLLONESHOT after its event was reported.
+// Returns zcall.ENOENT if fd is not registered.
+func (ep *Epoll) Modify(fd PollFd, events uint32, data uint64) error {
+	return ep.ctl(EPOLL_CTL_MOD, fd, events, data)
+}
+
+// Delete unregisters fd. Returns zcall.ENOENT if fd is not registered.
+//
+// A descriptor is also unregistered when its last duplicate is closed,
+// but not while another duplicate, for example in a child process,
+// remains open: delete descriptors before closing them.
+func (ep *Epoll) Delete(fd PollFd) error {
+	return ep.ctl(EPOLL_CTL_DEL, fd, 0, 0)
+}
+
+func (ep *Epoll) ctl(op uintptr, fd PollFd, events uint32, data uint64) error {
+	raw := ep.fd.Raw()
+	if raw < 0 {
+		return ErrClosed
+	}
+	target := fd.Fd()
+	if target < 0 {
+		return ErrClosed
+	}
+	return epollCtl(raw, op, int32(target), events, data)
+}
+
+// Wait waits until events are pending or timeout elapses, stores them into
+// events and returns their number. A negative timeout waits indefinitely;
+// zero returns at once. EINTR is handled as by Poll. It does not allocate.
+// As with Poll, the P of the calling goroutine is released while it waits.
+//
+// The timeout has nanosecond resolution where epoll_pwait2(2) is available
+// (Linux 5.11) and is rounded up to milliseconds otherwise.
+func (ep *Epoll) Wait(events []EpollEvent, timeout time.Duration, opts ...PollOption) (int, error) {
+	return ep.PWait(events, timeout, nil, opts...)
+}
+
+// PWait is like Wait, but replaces the signal mask of the calling thread
+// with mask for the duration of the wait, as PPoll does.
+func (ep *Epoll) PWait(events []EpollEvent, timeout time.Duration, mask *SigSet, opts ...PollOption) (int, error) {
+	raw := ep.fd.Raw()
+	if raw < 0 {
+		return 0, ErrClosed
+	}
+	if len(events) == 0 {
+		return 0, ErrInvalidParam
+	}
+	return retryInterrupted(timeout, opts, func(remaining int64) (int, error) {
+		return epollPwait(raw, events, remaining, mask)
+	})
+}
+
+// epollCreate creates a new epoll instance with EPOLL_CLOEXEC.
+func epollCreate() (FD, error) {
+	fd, errno := zcall.Syscall4(SYS_EPOLL_CREATE1, EPOLL_CLOEXEC, 0, 0, 0)
+	if errno != 0 {
+		return InvalidFD, errFromErrno(errno)
+	}
+	return FD(fd), nil
+}
+
+// epollCtl adds, modifies or removes fd in the epoll instance epfd.
+func epollCtl(epfd int32, op uintptr, fd int32, events uint32, data uint64) error {
+	var ev EpollEvent
+	ev.Events = events
+	ev.setData(data)
+	_, errno := zcall.Syscall4(
+		SYS_EPOLL_CTL,
+		uintptr(epfd),
+		op,
+		uintptr(fd),
+		uintptr(unsafe.Pointer(&ev)),
+	)
+	if errno != 0 {
+		return errFromErrno(errno)
+	}
+	return nil
+}
+
+// epollPwait2Missing records that the kernel lacks epoll_pwait2(2).
+var epollPwait2Missing atomic.Bool
+
+// epollPwait calls epoll_pwait2(2) once, or epoll_pwait(2) on kernels
+// without it. A negative timeout, in nanoseconds, blocks until an event
+// occurs; a nil mask leaves the signal mask unchanged. Waits other than a
+// zero-timeout probe release the P, as ppoll does.
+func epollPwait(epfd int32, events []EpollEvent, timeout int64, mask *SigSet) (int, error) {
+	var sigsetsize uintptr
+	if mask != nil {
+		sigsetsize = unsafe.Sizeof(*mask)
+	}
+	var n, errno uintptr
+	if !epollPwait2Missing.Load() {
+		var ts *timespec
+		if timeout >= 0 {
+			ts = new(timespec)
+			*ts = nsToTimespec(timeout)
+		}
+		if timeout == 0 {
+			n, errno = zcall.Syscall6(
+				SYS_EPOLL_PWAIT2,
+				uintptr(epfd),
+				uintptr(unsafe.Pointer(&events[0])),
+				uintptr(len(events)),
+				uintptr(unsafe.Pointer(ts)),
+				uintptr(unsafe.Pointer(mask)),
+				sigsetsize,
+			)
+		} else {
+			var e syscall.Errno
+			n, _, e = syscall.Syscall6(
+				SYS_EPOLL_PWAIT2,
+				uintptr(epfd),
+				uintptr(unsafe.Pointer(&events[0])),
+				uintptr(len(events)),
+				uintptr(unsafe.Pointer(ts)),
+				uintptr(unsafe.Pointer(mask)),
+				sigsetsize,
+			)
+			errno = uintptr(e)
+		}
+		if zcall.Errno(errno) != zcall.ENOSYS {
+			if errno != 0 {
+				return 0, errFromErrno(errno)
+			}
+			return int(n), nil
+		}
+		epollPwait2Missing.Store(true)
+	}
+	ms := -1
+	if timeout >= 0 {
+		// Round up so that the wait does not end before the timeout
+		ms = int(min(ceilDiv(timeout, int64(time.Millisecond)), math.MaxInt32))
+	}
+	if ms == 0 {
+		n, errno = zcall.Syscall6(
+			SYS_EPOLL_PWAIT,
+			uintptr(epfd),
+			uintptr(unsafe.Pointer(&events[0])),
+			uintptr(len(events)),
+			0,
+			uintptr(unsafe.Pointer(mask)),
+			sigsetsize,
+		)
+	} else {
+		var e syscall.Errno
+		n, _, e = syscall.Syscall6(
+			SYS_EPOLL_PWAIT,
+			uintptr(epfd),
+			uintptr(unsafe.Pointer(&events[0])),
+			uintptr(len(events)),
+			uintptr(ms),
+			uintptr(unsafe.Pointer(mask)),
+			sigsetsize,
+		)
+		errno = uintptr(e)
+	}
+	if errno != 0 {
+		return 0, errFromErrno(errno)
+	}
+	return int(n), nil
+}
+
+// epoll_create1 flags
+const (
+	EPOLL_CLOEXEC = 0x80000
+)
+
+// epoll_ctl operations
+const (
+	EPOLL_CTL_ADD = 1
+	EPOLL_CTL_DEL = 2
+	EPOLL_CTL_MOD = 3
+)
+
+// epoll event flags
+const (
+	EPOLLIN        = 0x1
+	EPOLLPRI       = 0x2
+	EPOLLOUT       = 0x4
+	EPOLLERR       = 0x8
+	EPOLLHUP       = 0x10
+	EPOLLRDHUP     = 0x2000
+	EPOLLEXCLUSIVE = 1 << 28 // Wake one of several epolls waiting on the same descriptor; Add only
+	EPOLLWAKEUP    = 1 << 29 // Prevent system suspend while the event is pending; needs CAP_BLOCK_SUSPEND
+	EPOLLONESHOT   = 1 << 30 // Disable the descriptor after one event until Modify
+	EPOLLET        = 1 << 31 // Edge-triggered notification
+)
+
+// Compile-time interface assertions
+var (
+	_ PollFd     = (*Epoll)(nil)
+	_ PollCloser = (*Epoll)(nil)
+)
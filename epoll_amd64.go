@@ -0,0 +1,24 @@
+// ©Hayabusa Cloud Co., Ltd. 2025. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+//go:build linux && amd64
+
+package iofd
+
+// EpollEvent matches struct epoll_event on amd64, which is packed
+// to 12 bytes with the data field at offset 4.
+type EpollEvent struct {
+	Events uint32 // Ready events, e.g. EPOLLIN | EPOLLHUP
+	data   [2]uint32
+}
+
+// UserData returns the value given when the descriptor was registered.
+func (e *EpollEvent) UserData() uint64 {
+	return uint64(e.data[0]) | uint64(e.data[1])<<32
+}
+
+func (e *EpollEvent) setData(v uint64) {
+	e.data[0] = uint32(v)
+	e.data[1] = uint32(v >> 32)
+}
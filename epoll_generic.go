@@ -0,0 +1,24 @@
+// ©Hayabusa Cloud Co., Ltd. 2025. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+//go:build linux && !amd64
+
+package iofd
+
+// EpollEvent matches struct epoll_event on architectures using natural
+// alignment: 16 bytes with the data field at offset 8.
+type EpollEvent struct {
+	Events uint32 // Ready events, e.g. EPOLLIN | EPOLLHUP
+	_      uint32
+	data   uint64
+}
+
+// UserData returns the value given when the descriptor was registered.
+func (e *EpollEvent) UserData() uint64 {
+	return e.data
+}
+
+func (e *EpollEvent) setData(v uint64) {
+	e.data = v
+}
@@ -26,4 +26,27 @@ var (
 
 	// ErrOverflow indicates a counter overflow (for eventfd).
 	ErrOverflow = errors.New("fd: counter overflow")
+
+	// ErrProcessExited indicates the process referred to by a pidfd
+	// has exited and been reaped.
+	ErrProcessExited = errors.New("fd: process exited")
+
+	// ErrTimeout indicates a deadline expired before the operation completed.
+	ErrTimeout = errors.New("fd: timeout")
+
+	// ErrWrongThread indicates a thread-bound handle was used from an OS
+	// thread other than the one it was created on.
+	ErrWrongThread = errors.New("fd: wrong thread")
+
+	// ErrSignalNotBlocked indicates a signal that must be blocked to be
+	// accepted through a signalfd is not blocked on the calling thread.
+	ErrSignalNotBlocked = errors.New("fd: signal not blocked")
+
+	// ErrSignalIgnored indicates a signal is ignored, so the kernel discards
+	// it before it can be accepted through a signalfd.
+	ErrSignalIgnored = errors.New("fd: signal ignored")
+
+	// ErrClockChanged indicates the realtime clock was set discontinuously
+	// while a timer armed with cancel-on-set was pending.
+	ErrClockChanged = errors.New("fd: clock changed")
 )
@@ -7,6 +7,7 @@
 package iofd
 
 import (
+	"context"
 	"encoding/binary"
 	"unsafe"
 
@@ -22,7 +23,8 @@ import (
 //
 // EventFD is created with O_NONBLOCK and O_CLOEXEC by default.
 type EventFD struct {
-	fd FD
+	fd       FD
+	blocking bool
 }
 
 // NewEventFD creates a new eventfd with the given initial value.
@@ -39,12 +41,27 @@ func NewEventFDSemaphore(initval uint) (*EventFD, error) {
 	return newEventFD(initval, EFD_SEMAPHORE|EFD_NONBLOCK|EFD_CLOEXEC)
 }
 
+// NewEventFDBlocking creates a new eventfd without EFD_NONBLOCK, for
+// consumers without an event loop. Wait then waits until the counter is
+// non-zero instead of returning iox.ErrWouldBlock; WaitContext can also be
+// canceled. Both park in ppoll(2) rather than in read(2), which the Go
+// runtime could not interrupt.
+func NewEventFDBlocking(initval uint) (*EventFD, error) {
+	return newEventFD(initval, EFD_CLOEXEC)
+}
+
+// NewEventFDSemaphoreBlocking creates a new eventfd in semaphore mode
+// without EFD_NONBLOCK. See NewEventFDBlocking.
+func NewEventFDSemaphoreBlocking(initval uint) (*EventFD, error) {
+	return newEventFD(initval, EFD_SEMAPHORE|EFD_CLOEXEC)
+}
+
 func newEventFD(initval uint, flags uintptr) (*EventFD, error) {
 	fd, errno := zcall.Eventfd2(uintptr(initval), flags)
 	if errno != 0 {
 		return nil, errFromErrno(errno)
 	}
-	return &EventFD{fd: FD(fd)}, nil
+	return &EventFD{fd: FD(fd), blocking: flags&EFD_NONBLOCK == 0}, nil
 }
 
 // Fd returns the underlying file descriptor.
@@ -92,17 +109,28 @@ func (e *EventFD) Signal(val uint64) error {
 // In semaphore mode, this decrements the counter by 1.
 //
 // Returns iox.ErrWouldBlock if the counter is zero (non-blocking mode).
+// In blocking mode, Wait parks in ppoll(2) until the counter is non-zero.
 func (e *EventFD) Wait() (uint64, error) {
 	raw := e.fd.Raw()
 	if raw < 0 {
 		return 0, ErrClosed
 	}
 	var buf [8]byte
-	n, errno := zcall.Read(uintptr(raw), buf[:])
+	n, err := readWait(raw, buf[:], e.blocking, errFromErrno)
+	if err != nil {
+		return 0, err
+	}
+	if n != 8 {
+		return 0, ErrInvalidParam
+	}
+	return binary.NativeEndian.Uint64(buf[:]), nil
+}
+
+// tryWait is Wait without waiting, also in blocking mode.
+func (e *EventFD) tryWait(raw int32) (uint64, error) {
+	var buf [8]byte
+	n, errno := readNow(raw, buf[:], e.blocking)
 	if errno != 0 {
-		if zcall.Errno(errno) == zcall.EAGAIN {
-			return 0, iox.ErrWouldBlock
-		}
 		return 0, errFromErrno(errno)
 	}
 	if n != 8 {
@@ -111,6 +139,26 @@ func (e *EventFD) Wait() (uint64, error) {
 	return binary.NativeEndian.Uint64(buf[:]), nil
 }
 
+// WaitContext is like Wait, but parks the calling goroutine until the
+// counter is non-zero or ctx is done, returning ctx.Err() in the latter
+// case. It works in both blocking and non-blocking mode.
+//
+// The wait uses ppoll(2) on the eventfd and on a second eventfd signaled
+// on cancellation. Like Poll, it releases the P of the calling goroutine
+// while parked.
+func (e *EventFD) WaitContext(ctx context.Context) (uint64, error) {
+	raw := e.fd.Raw()
+	if raw < 0 {
+		return 0, ErrClosed
+	}
+	var val uint64
+	err := waitContext(ctx, raw, POLLIN, func() (err error) {
+		val, err = e.tryWait(raw)
+		return err
+	})
+	return val, err
+}
+
 // Read reads the eventfd counter into p.
 // p must be at least 8 bytes. Only the first 8 bytes are used.
 // This is a lower-level interface; prefer Wait() for typical usage.
@@ -122,11 +170,7 @@ func (e *EventFD) Read(p []byte) (int, error) {
 	if raw < 0 {
 		return 0, ErrClosed
 	}
-	n, errno := zcall.Read(uintptr(raw), p[:8])
-	if errno != 0 {
-		return int(n), errFromErrno(errno)
-	}
-	return int(n), nil
+	return readWait(raw, p[:8], e.blocking, errFromErrno)
 }
 
 // Write writes a value to the eventfd from p.
@@ -7,7 +7,12 @@
 package iofd
 
 import (
+	"context"
+	"errors"
+	"os"
 	"testing"
+	"time"
+	"unsafe"
 
 	"code.hybscloud.com/iox"
 	"code.hybscloud.com/zcall"
@@ -1035,3 +1040,363 @@ func TestMemFD_TruncateAndSize(t *testing.T) {
 		t.Errorf("Size should be 1024, got %d", size)
 	}
 }
+
+// TestPidfdNamespaceIoctl tests the namespace type to ioctl mapping.
+func TestPidfdNamespaceIoctl(t *testing.T) {
+	if got := pidfdNamespaceIoctl(NamespaceNet); got != PIDFD_GET_NET_NAMESPACE {
+		t.Errorf("pidfdNamespaceIoctl(NamespaceNet) = %#x, want %#x", got, PIDFD_GET_NET_NAMESPACE)
+	}
+	for _, kind := range []NamespaceType{0, NamespaceNet | NamespaceUTS, 0x1} {
+		if got := pidfdNamespaceIoctl(kind); got != 0 {
+			t.Errorf("pidfdNamespaceIoctl(%#x) = %#x, want 0", uint32(kind), got)
+		}
+	}
+}
+
+// TestPidFD_TransferMemoryManyIovecs tests the heap-allocated iovec path.
+func TestPidFD_TransferMemoryManyIovecs(t *testing.T) {
+	p, err := NewPidFD(os.Getpid())
+	if err != nil {
+		t.Fatalf("NewPidFD failed: %v", err)
+	}
+	defer p.Close()
+
+	src := []byte("0123456789abcdefghij")
+	local := make([][]byte, maxStackIovecs+2)
+	for i := range local {
+		local[i] = make([]byte, 2)
+	}
+	remote := []RemoteIovec{{Base: uintptr(unsafe.Pointer(&src[0])), Len: uint64(len(src))}}
+	n, err := p.ReadMemory(remote, local)
+	if errors.Is(err, ErrPermission) {
+		t.Skipf("process_vm_readv not permitted: %v", err)
+	}
+	if err != nil {
+		t.Fatalf("ReadMemory failed: %v", err)
+	}
+	if n != len(src) {
+		t.Errorf("ReadMemory returned %d, want %d", n, len(src))
+	}
+	var got []byte
+	for _, b := range local {
+		got = append(got, b...)
+	}
+	if string(got) != string(src) {
+		t.Errorf("ReadMemory data = %q, want %q", got, src)
+	}
+}
+
+// TestParseFDInfo tests fdinfo parsing and field lookup.
+func TestParseFDInfo(t *testing.T) {
+	raw := "pos:\t12\nflags:\t02004002\nmnt_id:\t15\nino:\t1057\nclockid: 1\nticks: 0\n"
+	info := parseFDInfo(raw)
+	if info.Pos != 12 || info.Flags != 02004002 || info.MntID != 15 || info.Ino != 1057 {
+		t.Errorf("parseFDInfo = %+v", info)
+	}
+	if v, ok := info.Field("clockid"); !ok || v != "1" {
+		t.Errorf("Field(clockid) = (%q, %v), want (\"1\", true)", v, ok)
+	}
+	if _, ok := info.Field("missing"); ok {
+		t.Error("Field(missing) should not be found")
+	}
+}
+
+// TestIsNamespaceTarget tests nsfs link target detection.
+func TestIsNamespaceTarget(t *testing.T) {
+	tests := []struct {
+		target string
+		want   bool
+	}{
+		{"net:[4026531840]", true},
+		{"time:[4026531834]", true},
+		{"socket:[12345]", false},
+		{"pipe:[12345]", false},
+		{"net:[abc]", false},
+		{"net:[123", false},
+		{"anon_inode:[eventfd]", false},
+		{"/memfd:x (deleted)", false},
+	}
+	for _, tt := range tests {
+		if got := isNamespaceTarget(tt.target); got != tt.want {
+			t.Errorf("isNamespaceTarget(%q) = %v, want %v", tt.target, got, tt.want)
+		}
+	}
+}
+
+// TestProcfsHelpers tests the procfs helpers on missing paths.
+func TestProcfsHelpers(t *testing.T) {
+	if _, err := readFile("/proc/self/nonexistent"); err != zcall.ENOENT {
+		t.Errorf("readFile: expected ENOENT, got %v", err)
+	}
+	if _, err := readLink("/proc/self/nonexistent"); err != zcall.ENOENT {
+		t.Errorf("readLink: expected ENOENT, got %v", err)
+	}
+	if _, err := readDirNames("/proc/self/nonexistent"); err != zcall.ENOENT {
+		t.Errorf("readDirNames: expected ENOENT, got %v", err)
+	}
+	names, err := readDirNames("/proc/self/fd")
+	if err != nil || len(names) == 0 {
+		t.Errorf("readDirNames(/proc/self/fd) = (%v, %v)", names, err)
+	}
+	link, err := readLink("/proc/self/exe")
+	if err != nil || link == "" {
+		t.Errorf("readLink(/proc/self/exe) = (%q, %v)", link, err)
+	}
+}
+
+func TestExitStatusFromWait(t *testing.T) {
+	tests := []struct {
+		ws   int32
+		want ExitStatus
+	}{
+		{0x0000, ExitStatus{Code: CLD_EXITED, Status: 0}},
+		{0x0300, ExitStatus{Code: CLD_EXITED, Status: 3}},
+		{0xff00, ExitStatus{Code: CLD_EXITED, Status: 255}},
+		{0x0009, ExitStatus{Code: CLD_KILLED, Status: 9}},
+		{0x008b, ExitStatus{Code: CLD_DUMPED, Status: 11}},
+	}
+	for _, tt := range tests {
+		if got := exitStatusFromWait(tt.ws); got != tt.want {
+			t.Errorf("exitStatusFromWait(%#x) = %+v, want %+v", tt.ws, got, tt.want)
+		}
+	}
+	if size := unsafe.Sizeof(pidfdInfo{}); size != 64 {
+		t.Errorf("sizeof(pidfdInfo) = %d, want 64", size)
+	}
+}
+
+func TestContextFD(t *testing.T) {
+	c, err := newContextFD(context.Background())
+	if c != nil || err != nil {
+		t.Fatalf("newContextFD(Background) = (%v, %v), want (nil, nil)", c, err)
+	}
+	if c.raw() != -1 {
+		t.Errorf("nil contextFD raw() = %d, want -1", c.raw())
+	}
+	c.close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c, err = newContextFD(ctx)
+	if err != nil {
+		t.Fatalf("newContextFD failed: %v", err)
+	}
+	fds := []PollEntry{{Fd: c.raw(), Events: POLLIN}}
+	if n, err := ppoll(fds, &timespec{}, nil); n != 0 || err != nil {
+		t.Errorf("ppoll before cancel = (%d, %v), want (0, nil)", n, err)
+	}
+	cancel()
+	if n, err := pollWait(fds); n != 1 || err != nil || fds[0].Revents&POLLIN == 0 {
+		t.Errorf("pollWait after cancel = (%d, %v), revents %#x", n, err, fds[0].Revents)
+	}
+	c.close()
+	if c.efd.fd.Valid() {
+		t.Error("eventfd should be closed")
+	}
+}
+
+func TestSigeventLayout(t *testing.T) {
+	var ev sigevent
+	if size := unsafe.Sizeof(ev); size != 64 {
+		t.Errorf("sizeof(sigevent) = %d, want 64", size)
+	}
+	if off := unsafe.Offsetof(ev.tid); off != 16 {
+		t.Errorf("offset of sigev_notify_thread_id = %d, want 16", off)
+	}
+	if ts := nsToTimespec(3*1e9 + 5); ts.sec != 3 || ts.nsec != 5 {
+		t.Errorf("nsToTimespec = %+v, want {3 5}", ts)
+	}
+}
+
+func TestNow_Monotonic(t *testing.T) {
+	for range 100 {
+		before, err := clockGettime(CLOCK_MONOTONIC)
+		if err != nil {
+			t.Fatalf("clock_gettime(CLOCK_MONOTONIC) failed: %v", err)
+		}
+		now, err := Now(CLOCK_MONOTONIC)
+		if err != nil {
+			t.Fatalf("Now(CLOCK_MONOTONIC) failed: %v", err)
+		}
+		after, _ := clockGettime(CLOCK_MONOTONIC)
+		// Now never runs ahead of the clock and lags it only slightly
+		if now > after || now < before-int64(time.Millisecond) {
+			t.Fatalf("Now(CLOCK_MONOTONIC) = %d, want within [%d, %d]", now, before, after)
+		}
+	}
+	if n := testing.AllocsPerRun(100, func() { _, _ = Now(CLOCK_MONOTONIC) }); n != 0 {
+		t.Errorf("Now(CLOCK_MONOTONIC) allocates %v times", n)
+	}
+}
+
+func TestCPUClockIDs(t *testing.T) {
+	// Clocks derived from the caller's IDs measure the caller
+	self, err := clockGettime(CLOCK_PROCESS_CPUTIME_ID)
+	if err != nil {
+		t.Fatalf("clock_gettime(CLOCK_PROCESS_CPUTIME_ID) failed: %v", err)
+	}
+	byPid, err := clockGettime(processCPUClock(os.Getpid()))
+	if err != nil {
+		t.Fatalf("clock_gettime(processCPUClock) failed: %v", err)
+	}
+	if byPid < self {
+		t.Errorf("process clock went backwards: %d < %d", byPid, self)
+	}
+	if _, err := clockGettime(threadCPUClock(gettid())); err != nil {
+		t.Errorf("clock_gettime(threadCPUClock) failed: %v", err)
+	}
+	if n := onlineCPUs(); n < 1 {
+		t.Errorf("onlineCPUs = %d", n)
+	}
+}
+
+func TestTimerWheelAdvance(t *testing.T) {
+	// Simulate the wheel with one-nanosecond ticks and compare each timer
+	// with the tick at which it must fire
+	w := &TimerWheel{tick: 1, cur: 1 << 40, armed: -1}
+	rng := uint64(0x9e3779b97f4a7c15)
+	rand := func(n int64) int64 {
+		rng ^= rng << 13
+		rng ^= rng >> 7
+		rng ^= rng << 17
+		return int64(rng % uint64(n))
+	}
+
+	fired := make(map[*WheelTimer]int64)
+	var timers []*WheelTimer
+	for range 2000 {
+		// Distances spanning several levels, including already due timers
+		var d int64
+		switch rand(4) {
+		case 0:
+			d = rand(64) - 8
+		case 1:
+			d = rand(1 << 12)
+		case 2:
+			d = rand(1 << 24)
+		default:
+			d = rand(1 << 36)
+		}
+		tm := &WheelTimer{w: w}
+		w.start(tm, w.cur+d)
+		timers = append(timers, tm)
+
+		// Advance by steps of varying size
+		now := w.cur + rand(1<<uint(rand(30)))
+		w.advance(now)
+		for _, e := range w.expired {
+			fired[e] = now
+		}
+		w.expired = w.expired[:0]
+		for _, tm := range timers {
+			if _, ok := fired[tm]; !ok && tm.expires <= now {
+				t.Fatalf("timer expiring at %d still pending at %d", tm.expires, now)
+			}
+		}
+	}
+	for w.count > 0 {
+		next := w.next()
+		if next < 0 {
+			t.Fatalf("%d timers pending without a next tick", w.count)
+		}
+		w.advance(next)
+		for _, e := range w.expired {
+			fired[e] = next
+		}
+		w.expired = w.expired[:0]
+	}
+
+	for i, tm := range timers {
+		at, ok := fired[tm]
+		if !ok {
+			t.Fatalf("timer %d (expires %d) never fired", i, tm.expires)
+		}
+		if at < tm.expires {
+			t.Errorf("timer %d fired at %d, before its expiry %d", i, at, tm.expires)
+		}
+	}
+	if w.next() != -1 {
+		t.Errorf("next of empty wheel = %d, want -1", w.next())
+	}
+}
+
+func TestTimerWheelFarDeadline(t *testing.T) {
+	// Deadlines beyond the range of the top level are moved on until due
+	w := &TimerWheel{tick: 1, armed: -1}
+	tm := &WheelTimer{w: w}
+	w.start(tm, 3*wheelMaxDelta+5)
+	for steps := 0; w.count > 0; steps++ {
+		if steps > 1000 {
+			t.Fatal("far timer never fired")
+		}
+		w.advance(w.next())
+	}
+	if w.cur != 3*wheelMaxDelta+5 || len(w.expired) != 1 {
+		t.Errorf("fired at tick %d with %d expired, want %d", w.cur, len(w.expired), 3*wheelMaxDelta+5)
+	}
+	if ceilDiv(7, 2) != 4 || ceilDiv(8, 2) != 4 || ceilDiv(-3, 2) != -1 {
+		t.Error("ceilDiv rounds incorrectly")
+	}
+}
+
+func TestParseTimensOffsets(t *testing.T) {
+	o, err := parseTimensOffsets("monotonic      3600         5\nboottime      -10 500000000\n")
+	if err != nil {
+		t.Fatalf("parseTimensOffsets failed: %v", err)
+	}
+	want := TimensOffsets{Monotonic: time.Hour + 5, Boottime: -10*time.Second + 500*time.Millisecond}
+	if o != want {
+		t.Errorf("parsed %+v, want %+v", o, want)
+	}
+	// Numeric clock IDs as accepted when writing the file
+	if o, err := parseTimensOffsets("1 7 0\n7 0 9\n"); err != nil || o.Monotonic != 7*time.Second || o.Boottime != 9 {
+		t.Errorf("numeric clocks = (%+v, %v)", o, err)
+	}
+	if _, err := parseTimensOffsets("monotonic x 0\n"); err != ErrInvalidParam {
+		t.Errorf("malformed: expected ErrInvalidParam, got %v", err)
+	}
+
+	if got := want.ToHost(CLOCK_MONOTONIC, int64(2*time.Hour)); got != int64(time.Hour-5) {
+		t.Errorf("ToHost(MONOTONIC) = %d", got)
+	}
+	if got := want.FromHost(CLOCK_BOOTTIME_ALARM, 0); got != int64(want.Boottime) {
+		t.Errorf("FromHost(BOOTTIME_ALARM) = %d", got)
+	}
+	if got := want.ToHost(CLOCK_REALTIME, 42); got != 42 {
+		t.Errorf("ToHost(REALTIME) = %d, want unchanged", got)
+	}
+}
+
+func TestEpollPwaitFallback(t *testing.T) {
+	ep, err := NewEpoll()
+	if err != nil {
+		t.Fatalf("NewEpoll failed: %v", err)
+	}
+	defer ep.Close()
+	efd, err := NewEventFD(0)
+	if err != nil {
+		t.Fatalf("NewEventFD failed: %v", err)
+	}
+	defer efd.Close()
+	if err := ep.Add(efd, EPOLLIN, 9); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+
+	// Take the epoll_pwait path as on kernels without epoll_pwait2
+	missing := epollPwait2Missing.Load()
+	epollPwait2Missing.Store(true)
+	defer epollPwait2Missing.Store(missing)
+
+	events := make([]EpollEvent, 1)
+	start := time.Now()
+	if n, err := ep.Wait(events, 1500*time.Microsecond); n != 0 || err != nil {
+		t.Errorf("Wait until timeout = (%d, %v), want (0, nil)", n, err)
+	}
+	// The timeout is rounded up to whole milliseconds
+	if elapsed := time.Since(start); elapsed < 2*time.Millisecond {
+		t.Errorf("Wait returned after %v, want at least 2ms", elapsed)
+	}
+	_ = efd.Signal(1)
+	if n, err := ep.Wait(events, -1); n != 1 || err != nil || events[0].UserData() != 9 {
+		t.Errorf("Wait = (%d, %v), data %d; want the eventfd", n, err, events[0].UserData())
+	}
+}
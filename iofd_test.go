@@ -7,11 +7,28 @@
 package iofd_test
 
 import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"os/exec"
+	"os/signal"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
 	"testing"
 	"time"
+	"unsafe"
 
 	"code.hybscloud.com/iofd"
 	"code.hybscloud.com/iox"
+	"code.hybscloud.com/zcall"
 )
 
 // =============================================================================
@@ -230,6 +247,186 @@ func TestTimerFD_CreateBoottime(t *testing.T) {
 	}
 }
 
+func TestTimerFD_CreateAlarm(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		new  func() (*iofd.TimerFD, error)
+	}{
+		{"realtime", iofd.NewTimerFDAlarm},
+		{"boottime", iofd.NewTimerFDBoottimeAlarm},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			tfd, err := tc.new()
+			if err == iofd.ErrPermission {
+				t.Skipf("alarm clocks unavailable: %v", err)
+			}
+			if err != nil {
+				t.Fatalf("create failed: %v", err)
+			}
+			defer tfd.Close()
+
+			if err := tfd.Arm(int64(5*time.Millisecond), 0); err != nil {
+				t.Fatalf("Arm failed: %v", err)
+			}
+			if !epollWaitReadable(t, tfd.Fd(), time.Second) {
+				t.Fatal("alarm timer did not expire")
+			}
+			if n, err := tfd.Read(); err != nil || n != 1 {
+				t.Errorf("Read = (%d, %v), want (1, nil)", n, err)
+			}
+		})
+	}
+}
+
+func TestTimerFD_CreateClock(t *testing.T) {
+	tfd, err := iofd.NewTimerFDClock(iofd.CLOCK_MONOTONIC, iofd.TFD_NONBLOCK|iofd.TFD_CLOEXEC)
+	if err != nil {
+		t.Fatalf("NewTimerFDClock failed: %v", err)
+	}
+	defer tfd.Close()
+	if _, err := tfd.Read(); err != iox.ErrWouldBlock {
+		t.Errorf("Read of disarmed timer: expected ErrWouldBlock, got %v", err)
+	}
+
+	// timerfd rejects CPU-time clocks and unknown flags
+	if _, err := iofd.NewTimerFDClock(iofd.CLOCK_THREAD_CPUTIME_ID, iofd.TFD_NONBLOCK); err != iofd.ErrInvalidParam {
+		t.Errorf("CPU clock: expected ErrInvalidParam, got %v", err)
+	}
+	if _, err := iofd.NewTimerFDClock(iofd.CLOCK_MONOTONIC, iofd.TFD_TIMER_ABSTIME); err != iofd.ErrInvalidParam {
+		t.Errorf("TFD_TIMER_ABSTIME flag: expected ErrInvalidParam, got %v", err)
+	}
+}
+
+func TestTimerFD_ArmAtTime(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		new  func() (*iofd.TimerFD, error)
+	}{
+		{"monotonic", iofd.NewTimerFD},
+		{"realtime", iofd.NewTimerFDRealtime},
+		{"boottime", iofd.NewTimerFDBoottime},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			tfd, err := tc.new()
+			if err != nil {
+				t.Fatalf("create failed: %v", err)
+			}
+			defer tfd.Close()
+
+			const d = 20 * time.Millisecond
+			if err := tfd.ArmAtTime(time.Now().Add(d), 0); err != nil {
+				t.Fatalf("ArmAtTime failed: %v", err)
+			}
+			remaining, _, err := tfd.GetTime()
+			if err != nil {
+				t.Fatalf("GetTime failed: %v", err)
+			}
+			if remaining <= 0 || remaining > int64(d) {
+				t.Errorf("remaining = %v, want within (0, %v]", time.Duration(remaining), d)
+			}
+			if !epollWaitReadable(t, tfd.Fd(), time.Second) {
+				t.Fatal("timer did not expire")
+			}
+			if n, err := tfd.Read(); err != nil || n != 1 {
+				t.Errorf("Read = (%d, %v), want (1, nil)", n, err)
+			}
+
+			// A deadline in the past, even before the epoch, expires at once
+			for _, past := range []time.Time{time.Now().Add(-time.Hour), time.Unix(-5, 0)} {
+				if err := tfd.ArmAtTime(past, 0); err != nil {
+					t.Fatalf("ArmAtTime(%v) failed: %v", past, err)
+				}
+				if !epollWaitReadable(t, tfd.Fd(), time.Second) {
+					t.Fatalf("timer armed at %v did not expire", past)
+				}
+				if _, err := tfd.Read(); err != nil {
+					t.Errorf("Read failed: %v", err)
+				}
+			}
+
+			for _, bad := range []time.Time{{}, time.Date(3000, 1, 1, 0, 0, 0, 0, time.UTC)} {
+				if err := tfd.ArmAtTime(bad, 0); err != iofd.ErrInvalidParam {
+					t.Errorf("ArmAtTime(%v): expected ErrInvalidParam, got %v", bad, err)
+				}
+			}
+			if err := tfd.ArmAtTime(time.Now(), -time.Second); err != iofd.ErrInvalidParam {
+				t.Errorf("negative interval: expected ErrInvalidParam, got %v", err)
+			}
+		})
+	}
+}
+
+func TestTimerFD_Rearm(t *testing.T) {
+	tfd, err := iofd.NewTimerFD()
+	if err != nil {
+		t.Fatalf("NewTimerFD failed: %v", err)
+	}
+	defer tfd.Close()
+
+	// Disarmed timers report a zero previous setting
+	remaining, interval, err := tfd.Rearm(int64(time.Hour), int64(time.Second))
+	if err != nil || remaining != 0 || interval != 0 {
+		t.Fatalf("Rearm of disarmed timer = (%d, %d, %v), want (0, 0, nil)", remaining, interval, err)
+	}
+	remaining, interval, err = tfd.Rearm(int64(2*time.Hour), 0)
+	if err != nil {
+		t.Fatalf("Rearm failed: %v", err)
+	}
+	if remaining <= int64(59*time.Minute) || remaining > int64(time.Hour) || interval != int64(time.Second) {
+		t.Errorf("Rearm = (%v, %v), want about (1h, 1s)", time.Duration(remaining), time.Duration(interval))
+	}
+
+	remaining, interval, err = tfd.RearmAt(0, 0) // disarms
+	if err != nil || remaining <= int64(119*time.Minute) || interval != 0 {
+		t.Errorf("RearmAt = (%v, %v, %v), want about (2h, 0)", time.Duration(remaining), time.Duration(interval), err)
+	}
+
+	r, i, err := tfd.RearmDuration(time.Minute, time.Millisecond)
+	if err != nil || r != 0 || i != 0 {
+		t.Errorf("RearmDuration of disarmed timer = (%v, %v, %v), want (0, 0, nil)", r, i, err)
+	}
+	r, i, err = tfd.RearmDuration(0, 0)
+	if err != nil || r <= 59*time.Second || r > time.Minute || i != time.Millisecond {
+		t.Errorf("RearmDuration = (%v, %v, %v), want about (1m, 1ms)", r, i, err)
+	}
+
+	tfd.Close()
+	if _, _, err := tfd.Rearm(1, 0); err != iofd.ErrClosed {
+		t.Errorf("Rearm after Close: expected ErrClosed, got %v", err)
+	}
+	if err := tfd.ArmAtTime(time.Now(), 0); err != iofd.ErrClosed {
+		t.Errorf("ArmAtTime after Close: expected ErrClosed, got %v", err)
+	}
+}
+
+func TestTimerFD_ArmNegative(t *testing.T) {
+	tfd, err := iofd.NewTimerFD()
+	if err != nil {
+		t.Fatalf("NewTimerFD failed: %v", err)
+	}
+	defer tfd.Close()
+	for _, tc := range []struct {
+		name string
+		err  error
+	}{
+		{"Arm initial", tfd.Arm(-1, 0)},
+		{"Arm interval", tfd.Arm(int64(time.Second), -1)},
+		{"ArmAt deadline", tfd.ArmAt(-int64(time.Second), 0)},
+		{"ArmDuration", tfd.ArmDuration(-time.Millisecond, 0)},
+	} {
+		if tc.err != iofd.ErrInvalidParam {
+			t.Errorf("%s: expected ErrInvalidParam, got %v", tc.name, tc.err)
+		}
+	}
+	if _, _, err := tfd.RearmAt(1, -1); err != iofd.ErrInvalidParam {
+		t.Errorf("RearmAt interval: expected ErrInvalidParam, got %v", err)
+	}
+	// Rejected settings leave the timer untouched
+	if remaining, interval, err := tfd.GetTime(); err != nil || remaining != 0 || interval != 0 {
+		t.Errorf("GetTime = (%d, %d, %v), want disarmed", remaining, interval, err)
+	}
+}
+
 func TestTimerFD_ArmAndRead(t *testing.T) {
 	tfd, err := iofd.NewTimerFD()
 	if err != nil {
@@ -2283,3 +2480,4575 @@ func TestSigSet_OutOfRange(t *testing.T) {
 		t.Error("Has(-1) should return false")
 	}
 }
+
+// =============================================================================
+// Helper Process
+// =============================================================================
+
+// helperEnv selects the behavior of TestHelperProcess in a re-executed test binary.
+const helperEnv = "IOFD_TEST_HELPER"
+
+// helperCommand returns a command that re-executes the test binary as a helper
+// process running the given mode.
+func helperCommand(t *testing.T, mode string, args ...string) *exec.Cmd {
+	t.Helper()
+	cmd := exec.Command(os.Args[0], append([]string{"-test.run=^TestHelperProcess$", "--"}, args...)...)
+	cmd.Env = append(os.Environ(), helperEnv+"="+mode)
+	cmd.Stderr = os.Stderr
+	return cmd
+}
+
+// helperPattern is the memory content published by the "memory" helper.
+const helperPattern = "iofd-process-vm-pattern"
+
+// helperArgs returns the arguments passed to a helper process after "--".
+func helperArgs() []string {
+	for i, arg := range os.Args {
+		if arg == "--" {
+			return os.Args[i+1:]
+		}
+	}
+	return nil
+}
+
+// TestHelperProcess is not a real test. It is the entry point of processes
+// started by helperCommand.
+func TestHelperProcess(t *testing.T) {
+	mode := os.Getenv(helperEnv)
+	if mode == "" {
+		return
+	}
+	switch mode {
+	case "sleep":
+		// Block until the parent closes stdin
+		_, _ = io.Copy(io.Discard, os.Stdin)
+	case "stubborn":
+		// Ignore SIGTERM, report readiness and block until stdin is closed
+		signal.Ignore(syscall.SIGTERM)
+		fmt.Println("ready")
+		_, _ = io.Copy(io.Discard, os.Stdin)
+	case "interop-ignored":
+		// Report whether NewSignalFDInterop detects an inherited SIG_IGN
+		runtime.LockOSThread()
+		mask := iofd.SigSetOf(iofd.SIGHUP)
+		_, _ = iofd.BlockSignals(mask)
+		_, err := iofd.NewSignalFDInterop(mask)
+		fmt.Println(err)
+	case "spin":
+		// Consume CPU time until killed, bounded to ten seconds
+		for deadline := time.Now().Add(10 * time.Second); time.Now().Before(deadline); {
+		}
+	case "exit":
+		// Exit with the code given as the first argument
+		code, _ := strconv.Atoi(helperArgs()[0])
+		os.Exit(code)
+	case "linger":
+		// Sleep for the duration given as the first argument, then exit
+		// with the code given as the second argument
+		args := helperArgs()
+		d, _ := time.ParseDuration(args[0])
+		time.Sleep(d)
+		code, _ := strconv.Atoi(args[1])
+		os.Exit(code)
+	case "orphan":
+		// Start a lingering grandchild, report its PID and exit without
+		// waiting for it, leaving it orphaned
+		cmd := exec.Command(os.Args[0], "-test.run=^TestHelperProcess$", "--", helperArgs()[0], "7")
+		cmd.Env = append(os.Environ(), helperEnv+"=linger")
+		if err := cmd.Start(); err != nil {
+			os.Exit(4)
+		}
+		fmt.Printf("%d\n", cmd.Process.Pid)
+		os.Exit(3)
+	case "memory":
+		// Map a page holding helperPattern, report its address, then dump
+		// the pattern-sized prefix of the page for each line read from stdin
+		page, err := syscall.Mmap(-1, 0, os.Getpagesize(), syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_PRIVATE|syscall.MAP_ANONYMOUS)
+		if err != nil {
+			os.Exit(3)
+		}
+		copy(page, helperPattern)
+		fmt.Printf("%d\n", uintptr(unsafe.Pointer(&page[0])))
+		sc := bufio.NewScanner(os.Stdin)
+		for sc.Scan() {
+			fmt.Printf("%s\n", page[:len(helperPattern)])
+		}
+	case "semaphore":
+		// Take two units of the Semaphore inherited as fd 3, then release
+		// five
+		s, err := iofd.NewSemaphoreFd(3)
+		if err != nil {
+			os.Exit(3)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if s.Acquire(ctx, 2) != nil {
+			os.Exit(4)
+		}
+		if s.Release(5) != nil {
+			os.Exit(5)
+		}
+	case "mutex":
+		// Check that the Mutex inherited as fd 3 is held, report readiness,
+		// then wait to lock it and unlock it again
+		m, err := iofd.NewMutexFd(3)
+		if err != nil {
+			os.Exit(3)
+		}
+		if ok, err := m.TryLock(); ok || err != nil {
+			os.Exit(4)
+		}
+		fmt.Println("ready")
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if m.Lock(ctx) != nil || m.Unlock() != nil {
+			os.Exit(5)
+		}
+	default:
+		os.Exit(2)
+	}
+	os.Exit(0)
+}
+
+// =============================================================================
+// Namespace Tests
+// =============================================================================
+
+func TestPidFD_Namespace(t *testing.T) {
+	pfd, err := iofd.NewPidFD(os.Getpid())
+	if err != nil {
+		t.Fatalf("NewPidFD failed: %v", err)
+	}
+	defer pfd.Close()
+
+	kinds := []iofd.NamespaceType{
+		iofd.NamespaceMount, iofd.NamespaceNet, iofd.NamespacePID, iofd.NamespaceUTS,
+		iofd.NamespaceIPC, iofd.NamespaceUser, iofd.NamespaceCgroup, iofd.NamespaceTime,
+	}
+	for _, kind := range kinds {
+		t.Run(kind.String(), func(t *testing.T) {
+			ns, err := pfd.Namespace(kind)
+			if err != nil {
+				if err == zcall.ENOTTY || err == zcall.ENOENT {
+					t.Skipf("PIDFD_GET_%s_NAMESPACE not supported: %v", strings.ToUpper(kind.String()), err)
+				}
+				t.Fatalf("Namespace(%v) failed: %v", kind, err)
+			}
+			defer ns.Close()
+
+			typ, err := ns.Type()
+			if err != nil {
+				t.Fatalf("Type failed: %v", err)
+			}
+			if typ != kind {
+				t.Errorf("Type() = %v, want %v", typ, kind)
+			}
+
+			ino, err := ns.Inode()
+			if err != nil {
+				t.Fatalf("Inode failed: %v", err)
+			}
+			link, err := os.Readlink("/proc/self/ns/" + kind.String())
+			if err != nil {
+				t.Fatalf("Readlink failed: %v", err)
+			}
+			want := kind.String() + ":[" + strconv.FormatUint(ino, 10) + "]"
+			if link != want {
+				t.Errorf("namespace link = %q, want %q", link, want)
+			}
+		})
+	}
+}
+
+func TestPidFD_NamespaceInvalid(t *testing.T) {
+	pfd, err := iofd.NewPidFD(os.Getpid())
+	if err != nil {
+		t.Fatalf("NewPidFD failed: %v", err)
+	}
+
+	if _, err := pfd.Namespace(0); err != iofd.ErrInvalidParam {
+		t.Errorf("Namespace(0): expected ErrInvalidParam, got %v", err)
+	}
+	if _, err := pfd.Namespace(iofd.NamespaceNet | iofd.NamespaceUTS); err != iofd.ErrInvalidParam {
+		t.Errorf("Namespace(mask): expected ErrInvalidParam, got %v", err)
+	}
+
+	pfd.Close()
+	if _, err := pfd.Namespace(iofd.NamespaceNet); err != iofd.ErrClosed {
+		t.Errorf("Namespace on closed pidfd: expected ErrClosed, got %v", err)
+	}
+}
+
+func TestNamespaceFD_Close(t *testing.T) {
+	pfd, err := iofd.NewPidFD(os.Getpid())
+	if err != nil {
+		t.Fatalf("NewPidFD failed: %v", err)
+	}
+	defer pfd.Close()
+
+	ns, err := pfd.Namespace(iofd.NamespaceUTS)
+	if err != nil {
+		t.Skipf("Namespace not supported: %v", err)
+	}
+	if !ns.Valid() {
+		t.Error("NamespaceFD should be valid")
+	}
+	if err := ns.Close(); err != nil {
+		t.Errorf("Close failed: %v", err)
+	}
+	if ns.Valid() {
+		t.Error("NamespaceFD should not be valid after close")
+	}
+	if _, err := ns.Type(); err != iofd.ErrClosed {
+		t.Errorf("Type on closed: expected ErrClosed, got %v", err)
+	}
+	if _, err := ns.Inode(); err != iofd.ErrClosed {
+		t.Errorf("Inode on closed: expected ErrClosed, got %v", err)
+	}
+}
+
+func TestNamespaceType_String(t *testing.T) {
+	if got := iofd.NamespaceNet.String(); got != "net" {
+		t.Errorf("NamespaceNet.String() = %q, want \"net\"", got)
+	}
+	if got := (iofd.NamespaceNet | iofd.NamespaceUTS).String(); got != "unknown" {
+		t.Errorf("mask String() = %q, want \"unknown\"", got)
+	}
+}
+
+// startUserNamespaceChild starts a helper process in new user and UTS
+// namespaces. The test is skipped if unprivileged user namespaces are
+// not available.
+func startUserNamespaceChild(t *testing.T) (*exec.Cmd, io.Closer) {
+	t.Helper()
+	cmd := helperCommand(t, "sleep")
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		Cloneflags:  syscall.CLONE_NEWUSER | syscall.CLONE_NEWUTS,
+		UidMappings: []syscall.SysProcIDMap{{ContainerID: 0, HostID: os.Getuid(), Size: 1}},
+		GidMappings: []syscall.SysProcIDMap{{ContainerID: 0, HostID: os.Getgid(), Size: 1}},
+	}
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		t.Fatalf("StdinPipe failed: %v", err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Skipf("user namespaces not available: %v", err)
+	}
+	t.Cleanup(func() {
+		stdin.Close()
+		_ = cmd.Wait()
+	})
+	return cmd, stdin
+}
+
+func TestRunInNamespaces_UTS(t *testing.T) {
+	cmd, _ := startUserNamespaceChild(t)
+
+	pfd, err := iofd.NewPidFD(cmd.Process.Pid)
+	if err != nil {
+		t.Fatalf("NewPidFD failed: %v", err)
+	}
+	defer pfd.Close()
+
+	ns, err := pfd.Namespace(iofd.NamespaceUTS)
+	if err != nil {
+		t.Skipf("Namespace not supported: %v", err)
+	}
+	defer ns.Close()
+	childIno, err := ns.Inode()
+	if err != nil {
+		t.Fatalf("Inode failed: %v", err)
+	}
+
+	before, err := os.Hostname()
+	if err != nil {
+		t.Fatalf("Hostname failed: %v", err)
+	}
+
+	const name = "iofd-ns-test"
+	var inside string
+	err = iofd.RunInNamespaces(pfd, iofd.NamespaceUTS, func() error {
+		link, err := os.Readlink("/proc/thread-self/ns/uts")
+		if err != nil {
+			return err
+		}
+		if want := "uts:[" + strconv.FormatUint(childIno, 10) + "]"; link != want {
+			t.Errorf("thread uts namespace = %q, want %q", link, want)
+		}
+		if err := syscall.Sethostname([]byte(name)); err != nil {
+			return err
+		}
+		inside, err = os.Hostname()
+		return err
+	})
+	if errors.Is(err, iofd.ErrPermission) {
+		t.Skipf("setns not permitted: %v", err)
+	}
+	if err != nil {
+		t.Fatalf("RunInNamespaces failed: %v", err)
+	}
+	if inside != name {
+		t.Errorf("hostname inside namespace = %q, want %q", inside, name)
+	}
+
+	after, err := os.Hostname()
+	if err != nil {
+		t.Fatalf("Hostname failed: %v", err)
+	}
+	if after != before {
+		t.Errorf("hostname after RunInNamespaces = %q, want %q", after, before)
+	}
+}
+
+func TestRunInNamespaces_Errors(t *testing.T) {
+	pfd, err := iofd.NewPidFD(os.Getpid())
+	if err != nil {
+		t.Fatalf("NewPidFD failed: %v", err)
+	}
+
+	fn := func() error { return nil }
+	if err := iofd.RunInNamespaces(nil, iofd.NamespaceUTS, fn); err != iofd.ErrInvalidParam {
+		t.Errorf("nil pidfd: expected ErrInvalidParam, got %v", err)
+	}
+	if err := iofd.RunInNamespaces(pfd, 0, fn); err != iofd.ErrInvalidParam {
+		t.Errorf("empty kinds: expected ErrInvalidParam, got %v", err)
+	}
+	if err := iofd.RunInNamespaces(pfd, iofd.NamespaceUTS, nil); err != iofd.ErrInvalidParam {
+		t.Errorf("nil fn: expected ErrInvalidParam, got %v", err)
+	}
+	// A multi-threaded process cannot enter a time namespace
+	called := false
+	err = iofd.RunInNamespaces(pfd, iofd.NamespaceUTS|iofd.NamespaceTime, func() error {
+		called = true
+		return nil
+	})
+	if err != iofd.ErrInvalidParam || called {
+		t.Errorf("NamespaceTime: expected ErrInvalidParam without calling fn, got %v (called %v)", err, called)
+	}
+
+	// Entering our own namespace is allowed and fn's error is returned
+	errFn := errors.New("fn error")
+	err = iofd.RunInNamespaces(pfd, iofd.NamespaceUTS, func() error { return errFn })
+	if errors.Is(err, iofd.ErrPermission) {
+		t.Skipf("setns not permitted: %v", err)
+	}
+	if !errors.Is(err, errFn) {
+		t.Errorf("expected fn error, got %v", err)
+	}
+
+	pfd.Close()
+	if err := iofd.RunInNamespaces(pfd, iofd.NamespaceUTS, fn); err != iofd.ErrClosed {
+		t.Errorf("closed pidfd: expected ErrClosed, got %v", err)
+	}
+}
+
+// =============================================================================
+// PidFD Memory Tests
+// =============================================================================
+
+// memoryChild is a helper process exposing a page of memory.
+type memoryChild struct {
+	cmd   *exec.Cmd
+	stdin io.WriteCloser
+	out   *bufio.Scanner
+	addr  uintptr
+}
+
+func startMemoryChild(t *testing.T) *memoryChild {
+	t.Helper()
+	cmd := helperCommand(t, "memory")
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		t.Fatalf("StdinPipe failed: %v", err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatalf("StdoutPipe failed: %v", err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	c := &memoryChild{cmd: cmd, stdin: stdin, out: bufio.NewScanner(stdout)}
+	t.Cleanup(func() {
+		c.stdin.Close()
+		_ = c.cmd.Wait()
+	})
+	if !c.out.Scan() {
+		t.Fatalf("helper did not report address: %v", c.out.Err())
+	}
+	addr, err := strconv.ParseUint(c.out.Text(), 10, 64)
+	if err != nil {
+		t.Fatalf("bad address %q: %v", c.out.Text(), err)
+	}
+	c.addr = uintptr(addr)
+	return c
+}
+
+// dump asks the helper to print its pattern-sized memory prefix.
+func (c *memoryChild) dump(t *testing.T) string {
+	t.Helper()
+	if _, err := io.WriteString(c.stdin, "dump\n"); err != nil {
+		t.Fatalf("write to helper failed: %v", err)
+	}
+	if !c.out.Scan() {
+		t.Fatalf("helper did not answer: %v", c.out.Err())
+	}
+	return c.out.Text()
+}
+
+func TestPidFD_ReadWriteMemory(t *testing.T) {
+	c := startMemoryChild(t)
+
+	pfd, err := iofd.NewPidFD(c.cmd.Process.Pid)
+	if err != nil {
+		t.Fatalf("NewPidFD failed: %v", err)
+	}
+	defer pfd.Close()
+
+	// Scatter the remote pattern into two local buffers
+	head := make([]byte, 4)
+	tail := make([]byte, len(helperPattern)-4)
+	remote := []iofd.RemoteIovec{{Base: c.addr, Len: uint64(len(helperPattern))}}
+	n, err := pfd.ReadMemory(remote, [][]byte{head, tail})
+	if errors.Is(err, iofd.ErrPermission) {
+		t.Skipf("process_vm_readv not permitted: %v", err)
+	}
+	if err != nil {
+		t.Fatalf("ReadMemory failed: %v", err)
+	}
+	if n != len(helperPattern) {
+		t.Errorf("ReadMemory returned %d, want %d", n, len(helperPattern))
+	}
+	if got := string(head) + string(tail); got != helperPattern {
+		t.Errorf("ReadMemory data = %q, want %q", got, helperPattern)
+	}
+
+	// Overwrite the first bytes and have the child report its memory
+	n, err = pfd.WriteMemory([]iofd.RemoteIovec{{Base: c.addr, Len: 4}}, [][]byte{[]byte("IOFD")})
+	if err != nil {
+		t.Fatalf("WriteMemory failed: %v", err)
+	}
+	if n != 4 {
+		t.Errorf("WriteMemory returned %d, want 4", n)
+	}
+	if got, want := c.dump(t), "IOFD"+helperPattern[4:]; got != want {
+		t.Errorf("child memory = %q, want %q", got, want)
+	}
+}
+
+func TestPidFD_ReadMemoryExited(t *testing.T) {
+	c := startMemoryChild(t)
+
+	pfd, err := iofd.NewPidFD(c.cmd.Process.Pid)
+	if err != nil {
+		t.Fatalf("NewPidFD failed: %v", err)
+	}
+	defer pfd.Close()
+
+	c.stdin.Close()
+	if err := c.cmd.Wait(); err != nil {
+		t.Fatalf("helper failed: %v", err)
+	}
+
+	buf := make([]byte, len(helperPattern))
+	remote := []iofd.RemoteIovec{{Base: c.addr, Len: uint64(len(buf))}}
+	if _, err := pfd.ReadMemory(remote, [][]byte{buf}); err != iofd.ErrProcessExited {
+		t.Errorf("ReadMemory after exit: expected ErrProcessExited, got %v", err)
+	}
+	if _, err := pfd.WriteMemory(remote, [][]byte{buf}); err != iofd.ErrProcessExited {
+		t.Errorf("WriteMemory after exit: expected ErrProcessExited, got %v", err)
+	}
+}
+
+func TestPidFD_Madvise(t *testing.T) {
+	c := startMemoryChild(t)
+
+	pfd, err := iofd.NewPidFD(c.cmd.Process.Pid)
+	if err != nil {
+		t.Fatalf("NewPidFD failed: %v", err)
+	}
+	defer pfd.Close()
+
+	page := uint64(os.Getpagesize())
+	n, err := pfd.Madvise([]iofd.RemoteIovec{{Base: c.addr, Len: page}}, iofd.MADV_COLD)
+	if errors.Is(err, iofd.ErrPermission) || err == zcall.ENOSYS {
+		t.Skipf("process_madvise not available: %v", err)
+	}
+	if err != nil {
+		t.Fatalf("Madvise failed: %v", err)
+	}
+	if uint64(n) != page {
+		t.Errorf("Madvise returned %d, want %d", n, page)
+	}
+
+	// Unsupported advice is rejected by the kernel
+	if _, err := pfd.Madvise([]iofd.RemoteIovec{{Base: c.addr, Len: page}}, 4); err != iofd.ErrInvalidParam {
+		t.Errorf("Madvise(MADV_DONTNEED): expected ErrInvalidParam, got %v", err)
+	}
+
+	// The memory content survives MADV_COLD
+	if got := c.dump(t); got != helperPattern {
+		t.Errorf("child memory = %q, want %q", got, helperPattern)
+	}
+}
+
+func TestPidFD_MemoryEdgeCases(t *testing.T) {
+	pfd, err := iofd.NewPidFD(os.Getpid())
+	if err != nil {
+		t.Fatalf("NewPidFD failed: %v", err)
+	}
+
+	if n, err := pfd.ReadMemory(nil, nil); n != 0 || err != nil {
+		t.Errorf("ReadMemory(nil) = (%d, %v), want (0, nil)", n, err)
+	}
+	if n, err := pfd.Madvise(nil, iofd.MADV_COLD); n != 0 || err != nil {
+		t.Errorf("Madvise(nil) = (%d, %v), want (0, nil)", n, err)
+	}
+
+	pfd.Close()
+	buf := make([]byte, 8)
+	remote := []iofd.RemoteIovec{{Base: uintptr(unsafe.Pointer(&buf[0])), Len: 8}}
+	if _, err := pfd.ReadMemory(remote, [][]byte{buf}); err != iofd.ErrClosed {
+		t.Errorf("ReadMemory on closed: expected ErrClosed, got %v", err)
+	}
+	if _, err := pfd.WriteMemory(remote, [][]byte{buf}); err != iofd.ErrClosed {
+		t.Errorf("WriteMemory on closed: expected ErrClosed, got %v", err)
+	}
+	if _, err := pfd.Madvise(remote, iofd.MADV_COLD); err != iofd.ErrClosed {
+		t.Errorf("Madvise on closed: expected ErrClosed, got %v", err)
+	}
+}
+
+// =============================================================================
+// PidFD Descriptor Enumeration Tests
+// =============================================================================
+
+func TestPidFD_ListFDs(t *testing.T) {
+	efd, err := iofd.NewEventFD(7)
+	if err != nil {
+		t.Fatalf("NewEventFD failed: %v", err)
+	}
+	defer efd.Close()
+
+	pfd, err := iofd.NewPidFD(os.Getpid())
+	if err != nil {
+		t.Fatalf("NewPidFD failed: %v", err)
+	}
+	defer pfd.Close()
+
+	fds, err := pfd.ListFDs()
+	if err != nil {
+		t.Fatalf("ListFDs failed: %v", err)
+	}
+	var found bool
+	for _, e := range fds {
+		if e.Num != efd.Fd() {
+			continue
+		}
+		found = true
+		if e.Target != "anon_inode:[eventfd]" {
+			t.Errorf("Target = %q, want anon_inode:[eventfd]", e.Target)
+		}
+		if e.Info.Flags&iofd.O_NONBLOCK == 0 {
+			t.Errorf("Flags = %#o, want O_NONBLOCK set", e.Info.Flags)
+		}
+		if e.Info.Ino == 0 {
+			t.Error("Ino should be non-zero")
+		}
+		if v, ok := e.Info.Field("eventfd-count"); !ok || v != "7" {
+			t.Errorf("eventfd-count = %q (%v), want \"7\"", v, ok)
+		}
+	}
+	if !found {
+		t.Errorf("ListFDs did not report eventfd %d", efd.Fd())
+	}
+}
+
+func TestPidFD_ListFDsExited(t *testing.T) {
+	cmd := helperCommand(t, "sleep")
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		t.Fatalf("StdinPipe failed: %v", err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	pfd, err := iofd.NewPidFD(cmd.Process.Pid)
+	if err != nil {
+		t.Fatalf("NewPidFD failed: %v", err)
+	}
+	defer pfd.Close()
+
+	fds, err := pfd.ListFDs()
+	if err != nil {
+		t.Fatalf("ListFDs failed: %v", err)
+	}
+	if len(fds) < 3 {
+		t.Errorf("ListFDs returned %d entries, want at least stdin/stdout/stderr", len(fds))
+	}
+
+	stdin.Close()
+	_ = cmd.Wait()
+	if _, err := pfd.ListFDs(); err != iofd.ErrProcessExited {
+		t.Errorf("ListFDs after exit: expected ErrProcessExited, got %v", err)
+	}
+
+	pfd.Close()
+	if _, err := pfd.ListFDs(); err != iofd.ErrClosed {
+		t.Errorf("ListFDs on closed: expected ErrClosed, got %v", err)
+	}
+}
+
+func TestPidFD_GetHandle(t *testing.T) {
+	pfd, err := iofd.NewPidFD(os.Getpid())
+	if err != nil {
+		t.Fatalf("NewPidFD failed: %v", err)
+	}
+	defer pfd.Close()
+
+	get := func(t *testing.T, fd int) iofd.PollCloser {
+		t.Helper()
+		h, err := pfd.GetHandle(fd)
+		if errors.Is(err, iofd.ErrPermission) {
+			t.Skipf("pidfd_getfd not permitted: %v", err)
+		}
+		if err != nil {
+			t.Fatalf("GetHandle failed: %v", err)
+		}
+		t.Cleanup(func() { h.Close() })
+		if h.Fd() == fd {
+			t.Errorf("GetHandle returned the original descriptor %d", fd)
+		}
+		return h
+	}
+
+	t.Run("EventFD", func(t *testing.T) {
+		efd, err := iofd.NewEventFD(3)
+		if err != nil {
+			t.Fatalf("NewEventFD failed: %v", err)
+		}
+		defer efd.Close()
+		h, ok := get(t, efd.Fd()).(*iofd.EventFD)
+		if !ok {
+			t.Fatal("expected *EventFD")
+		}
+		// Both descriptors share the counter
+		if val, err := h.Wait(); err != nil || val != 3 {
+			t.Errorf("Wait = (%d, %v), want (3, nil)", val, err)
+		}
+	})
+
+	t.Run("TimerFD", func(t *testing.T) {
+		tfd, err := iofd.NewTimerFDBoottime()
+		if err != nil {
+			t.Fatalf("NewTimerFDBoottime failed: %v", err)
+		}
+		defer tfd.Close()
+		h, ok := get(t, tfd.Fd()).(*iofd.TimerFD)
+		if !ok {
+			t.Fatal("expected *TimerFD")
+		}
+		if h.Clock() != iofd.CLOCK_BOOTTIME {
+			t.Errorf("Clock = %d, want CLOCK_BOOTTIME", h.Clock())
+		}
+	})
+
+	t.Run("SignalFD", func(t *testing.T) {
+		var mask iofd.SigSet
+		mask.Add(iofd.SIGUSR1)
+		mask.Add(iofd.SIGUSR2)
+		sfd, err := iofd.NewSignalFD(mask)
+		if err != nil {
+			t.Fatalf("NewSignalFD failed: %v", err)
+		}
+		defer sfd.Close()
+		h, ok := get(t, sfd.Fd()).(*iofd.SignalFD)
+		if !ok {
+			t.Fatal("expected *SignalFD")
+		}
+		if h.Mask() != mask {
+			t.Errorf("Mask() = %#x, want %#x", uint64(h.Mask()), uint64(mask))
+		}
+	})
+
+	t.Run("PidFD", func(t *testing.T) {
+		h, ok := get(t, pfd.Fd()).(*iofd.PidFD)
+		if !ok {
+			t.Fatal("expected *PidFD")
+		}
+		if h.PID() != os.Getpid() {
+			t.Errorf("PID() = %d, want %d", h.PID(), os.Getpid())
+		}
+	})
+
+	t.Run("MemFD", func(t *testing.T) {
+		mfd, err := iofd.NewMemFD("iofd-handle")
+		if err != nil {
+			t.Fatalf("NewMemFD failed: %v", err)
+		}
+		defer mfd.Close()
+		h, ok := get(t, mfd.Fd()).(*iofd.MemFD)
+		if !ok {
+			t.Fatal("expected *MemFD")
+		}
+		if h.Name() != "iofd-handle" {
+			t.Errorf("Name() = %q, want \"iofd-handle\"", h.Name())
+		}
+	})
+
+	t.Run("NamespaceFD", func(t *testing.T) {
+		ns, err := pfd.Namespace(iofd.NamespaceNet)
+		if err != nil {
+			t.Skipf("Namespace not supported: %v", err)
+		}
+		defer ns.Close()
+		if _, ok := get(t, ns.Fd()).(*iofd.NamespaceFD); !ok {
+			t.Fatal("expected *NamespaceFD")
+		}
+	})
+
+	t.Run("Other", func(t *testing.T) {
+		r, w, err := os.Pipe()
+		if err != nil {
+			t.Fatalf("Pipe failed: %v", err)
+		}
+		defer r.Close()
+		defer w.Close()
+		if _, ok := get(t, int(r.Fd())).(*iofd.FD); !ok {
+			t.Fatal("expected *FD")
+		}
+	})
+}
+
+func TestPidFD_GetHandleErrors(t *testing.T) {
+	pfd, err := iofd.NewPidFD(os.Getpid())
+	if err != nil {
+		t.Fatalf("NewPidFD failed: %v", err)
+	}
+	if _, err := pfd.GetHandle(-1); err == nil {
+		t.Error("GetHandle(-1) should fail")
+	}
+	pfd.Close()
+	if _, err := pfd.GetHandle(0); err != iofd.ErrClosed {
+		t.Errorf("GetHandle on closed: expected ErrClosed, got %v", err)
+	}
+}
+
+// =============================================================================
+// PidFD Wait and Reaper Tests
+// =============================================================================
+
+func TestPidFD_Wait(t *testing.T) {
+	cmd := helperCommand(t, "linger", "50ms", "5")
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer cmd.Process.Release()
+
+	pfd, err := iofd.NewPidFD(cmd.Process.Pid)
+	if err != nil {
+		t.Fatalf("NewPidFD failed: %v", err)
+	}
+	defer pfd.Close()
+
+	// The helper is still running
+	if _, err := pfd.Wait(); err != iox.ErrWouldBlock {
+		t.Fatalf("Wait on running child: expected ErrWouldBlock, got %v", err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		status, err := pfd.Wait()
+		if err == iox.ErrWouldBlock && time.Now().Before(deadline) {
+			time.Sleep(5 * time.Millisecond)
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Wait failed: %v", err)
+		}
+		if !status.Exited() || status.ExitCode() != 5 {
+			t.Errorf("status = %v, want exit status 5", status)
+		}
+		break
+	}
+
+	// The child has been reaped
+	if _, err := pfd.Wait(); err != zcall.ECHILD {
+		t.Errorf("Wait after reap: expected ECHILD, got %v", err)
+	}
+}
+
+func TestPidFD_WaitBlocking(t *testing.T) {
+	cmd := helperCommand(t, "sleep")
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		t.Fatalf("StdinPipe failed: %v", err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer cmd.Process.Release()
+
+	pfd, err := iofd.NewPidFDBlocking(cmd.Process.Pid)
+	if err != nil {
+		t.Fatalf("NewPidFDBlocking failed: %v", err)
+	}
+	defer pfd.Close()
+
+	if err := pfd.SendSignal(iofd.SIGKILL); err != nil {
+		t.Fatalf("SendSignal failed: %v", err)
+	}
+	status, err := pfd.Wait()
+	stdin.Close()
+	if err != nil {
+		t.Fatalf("Wait failed: %v", err)
+	}
+	if !status.Signaled() || status.Signal() != iofd.SIGKILL {
+		t.Errorf("status = %v, want killed by SIGKILL", status)
+	}
+}
+
+func TestPidFD_WaitBlockingGC(t *testing.T) {
+	cmd := helperCommand(t, "linger", "200ms", "0")
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer cmd.Process.Release()
+
+	pfd, err := iofd.NewPidFDBlocking(cmd.Process.Pid)
+	if err != nil {
+		t.Fatalf("NewPidFDBlocking failed: %v", err)
+	}
+	defer pfd.Close()
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := pfd.Wait()
+		done <- err
+	}()
+	// A Wait parked in the kernel must not stall the stop-the-world phase
+	time.Sleep(20 * time.Millisecond)
+	runtime.GC()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Wait failed: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Wait did not return")
+	}
+}
+
+func TestPidFD_WaitReleasesP(t *testing.T) {
+	cmd := helperCommand(t, "sleep")
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		t.Fatalf("StdinPipe failed: %v", err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer cmd.Process.Release()
+
+	pfd, err := iofd.NewPidFDBlocking(cmd.Process.Pid)
+	if err != nil {
+		t.Fatalf("NewPidFDBlocking failed: %v", err)
+	}
+	defer pfd.Close()
+
+	checkReleasesP(t, func() error {
+		_, err := pfd.Wait()
+		return err
+	}, func() { stdin.Close() })
+}
+
+func TestPidFD_WaitClosed(t *testing.T) {
+	pfd, err := iofd.NewPidFD(os.Getpid())
+	if err != nil {
+		t.Fatalf("NewPidFD failed: %v", err)
+	}
+	// Not a child of the calling process
+	if _, err := pfd.Wait(); err != zcall.ECHILD {
+		t.Errorf("Wait on self: expected ECHILD, got %v", err)
+	}
+	pfd.Close()
+	if _, err := pfd.Wait(); err != iofd.ErrClosed {
+		t.Errorf("Wait on closed: expected ErrClosed, got %v", err)
+	}
+}
+
+func TestExitStatus(t *testing.T) {
+	tests := []struct {
+		status   iofd.ExitStatus
+		exited   bool
+		code     int
+		signaled bool
+		signal   int
+		str      string
+	}{
+		{iofd.ExitStatus{Code: iofd.CLD_EXITED, Status: 0}, true, 0, false, 0, "exit status 0"},
+		{iofd.ExitStatus{Code: iofd.CLD_EXITED, Status: 3}, true, 3, false, 0, "exit status 3"},
+		{iofd.ExitStatus{Code: iofd.CLD_KILLED, Status: 9}, false, -1, true, 9, "signal: 9"},
+		{iofd.ExitStatus{Code: iofd.CLD_DUMPED, Status: 11}, false, -1, true, 11, "signal: 11 (core dumped)"},
+		{iofd.ExitStatus{Code: iofd.CLD_STOPPED, Status: 19}, false, -1, false, 19, "stop signal: 19"},
+		{iofd.ExitStatus{Code: iofd.CLD_CONTINUED, Status: 18}, false, -1, false, 0, "continued"},
+		{iofd.ExitStatus{}, false, -1, false, 0, "unknown status 0"},
+	}
+	for _, tt := range tests {
+		s := tt.status
+		if s.Exited() != tt.exited || s.ExitCode() != tt.code || s.Signaled() != tt.signaled ||
+			s.Signal() != tt.signal || s.String() != tt.str {
+			t.Errorf("%+v: got (%v, %d, %v, %d, %q)", s, s.Exited(), s.ExitCode(), s.Signaled(), s.Signal(), s.String())
+		}
+	}
+	if !(iofd.ExitStatus{Code: iofd.CLD_DUMPED}).CoreDumped() {
+		t.Error("CLD_DUMPED should report CoreDumped")
+	}
+	if !(iofd.ExitStatus{Code: iofd.CLD_TRAPPED}).Stopped() {
+		t.Error("CLD_TRAPPED should report Stopped")
+	}
+	if !(iofd.ExitStatus{Code: iofd.CLD_CONTINUED}).Continued() {
+		t.Error("CLD_CONTINUED should report Continued")
+	}
+}
+
+// epollWaitReadable waits until fd is readable using a separate epoll
+// instance, as an external event loop would.
+func epollWaitReadable(t *testing.T, fd int, timeout time.Duration) bool {
+	t.Helper()
+	ep, err := syscall.EpollCreate1(syscall.EPOLL_CLOEXEC)
+	if err != nil {
+		t.Fatalf("EpollCreate1 failed: %v", err)
+	}
+	defer syscall.Close(ep)
+	ev := syscall.EpollEvent{Events: syscall.EPOLLIN, Fd: int32(fd)}
+	if err := syscall.EpollCtl(ep, syscall.EPOLL_CTL_ADD, fd, &ev); err != nil {
+		t.Fatalf("EpollCtl failed: %v", err)
+	}
+	events := make([]syscall.EpollEvent, 1)
+	deadline := time.Now().Add(timeout)
+	for {
+		n, err := syscall.EpollWait(ep, events, int(time.Until(deadline).Milliseconds()))
+		if err == syscall.EINTR {
+			continue
+		}
+		if err != nil {
+			t.Fatalf("EpollWait failed: %v", err)
+		}
+		return n > 0
+	}
+}
+
+func TestReaper_Orphans(t *testing.T) {
+	r, err := iofd.NewReaper()
+	if err != nil {
+		t.Fatalf("NewReaper failed: %v", err)
+	}
+	defer r.Close()
+
+	cmd := helperCommand(t, "orphan", "200ms")
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatalf("StdoutPipe failed: %v", err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	// The reaper owns the child; do not Wait for it through os/exec
+	childPID := cmd.Process.Pid
+	defer cmd.Process.Release()
+
+	sc := bufio.NewScanner(stdout)
+	if !sc.Scan() {
+		t.Fatalf("helper did not report grandchild PID: %v", sc.Err())
+	}
+	grandchildPID, err := strconv.Atoi(sc.Text())
+	if err != nil {
+		t.Fatalf("bad PID %q: %v", sc.Text(), err)
+	}
+
+	adopted := map[int]bool{}
+	exited := map[int]iofd.ExitStatus{}
+	events := make([]iofd.ReaperEvent, 2)
+	deadline := time.Now().Add(10 * time.Second)
+	// Other tests may leave children behind; only ours are checked
+	reaped := func() bool {
+		_, child := exited[childPID]
+		_, orphan := exited[grandchildPID]
+		return child && orphan
+	}
+	for !reaped() && time.Now().Before(deadline) {
+		n, err := r.Reap(events)
+		if err == iox.ErrWouldBlock {
+			if !epollWaitReadable(t, r.Fd(), time.Until(deadline)) {
+				break
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Reap failed: %v", err)
+		}
+		for _, ev := range events[:n] {
+			switch ev.Kind {
+			case iofd.ReaperAdopted:
+				adopted[ev.PID] = true
+			case iofd.ReaperExited:
+				if !adopted[ev.PID] {
+					t.Errorf("pid %d exited before being adopted", ev.PID)
+				}
+				exited[ev.PID] = ev.Status
+			}
+		}
+	}
+
+	if st, ok := exited[childPID]; !ok || st.ExitCode() != 3 {
+		t.Errorf("child %d: status = %v (reaped %v), want exit status 3", childPID, st, ok)
+	}
+	if st, ok := exited[grandchildPID]; !ok || st.ExitCode() != 7 {
+		t.Errorf("orphan %d: status = %v (reaped %v), want exit status 7", grandchildPID, st, ok)
+	}
+	for _, pid := range r.Children() {
+		if pid == childPID || pid == grandchildPID {
+			t.Errorf("Children() = %v, still tracks reaped pid %d", r.Children(), pid)
+		}
+	}
+}
+
+func TestReaper_Close(t *testing.T) {
+	r, err := iofd.NewReaper()
+	if err != nil {
+		t.Fatalf("NewReaper failed: %v", err)
+	}
+	if r.Fd() < 0 {
+		t.Errorf("Fd() returned invalid fd: %d", r.Fd())
+	}
+	if n, err := r.Reap(nil); n != 0 || err != nil {
+		t.Errorf("Reap(nil) = (%d, %v), want (0, nil)", n, err)
+	}
+	if err := r.Close(); err != nil {
+		t.Errorf("Close failed: %v", err)
+	}
+	if _, err := r.Reap(make([]iofd.ReaperEvent, 1)); err != iofd.ErrClosed {
+		t.Errorf("Reap on closed: expected ErrClosed, got %v", err)
+	}
+}
+
+// =============================================================================
+// PidFD Terminate Tests
+// =============================================================================
+
+// startTerminateChild starts a helper in the given mode and opens a pidfd
+// for it. A "stubborn" helper is waited for until it ignores SIGTERM.
+func startTerminateChild(t *testing.T, mode string) (*exec.Cmd, *iofd.PidFD) {
+	t.Helper()
+	cmd := helperCommand(t, mode)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		t.Fatalf("StdinPipe failed: %v", err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatalf("StdoutPipe failed: %v", err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	pfd, err := iofd.NewPidFD(cmd.Process.Pid)
+	if err != nil {
+		cmd.Process.Kill()
+		t.Fatalf("NewPidFD failed: %v", err)
+	}
+	t.Cleanup(func() {
+		stdin.Close()
+		_ = pfd.SendSignal(iofd.SIGKILL)
+		// Fails harmlessly if Terminate has already reaped the child
+		_ = cmd.Wait()
+		pfd.Close()
+	})
+	if mode == "stubborn" {
+		if !bufio.NewScanner(stdout).Scan() {
+			t.Fatal("helper did not become ready")
+		}
+	}
+	return cmd, pfd
+}
+
+func TestPidFD_TerminateDefault(t *testing.T) {
+	_, pfd := startTerminateChild(t, "sleep")
+
+	status, err := pfd.Terminate(context.Background(), iofd.TerminatePolicy{})
+	if err != nil {
+		t.Fatalf("Terminate failed: %v", err)
+	}
+	if !status.Signaled() || status.Signal() != iofd.SIGTERM {
+		t.Errorf("status = %v, want killed by SIGTERM", status)
+	}
+}
+
+func TestPidFD_TerminateEscalates(t *testing.T) {
+	_, pfd := startTerminateChild(t, "stubborn")
+
+	policy := iofd.TerminatePolicy{Steps: []iofd.TerminateStep{
+		{Signal: iofd.SIGTERM, Grace: 100 * time.Millisecond},
+		{Signal: iofd.SIGKILL},
+	}}
+	start := time.Now()
+	status, err := pfd.Terminate(context.Background(), policy)
+	if err != nil {
+		t.Fatalf("Terminate failed: %v", err)
+	}
+	if !status.Signaled() || status.Signal() != iofd.SIGKILL {
+		t.Errorf("status = %v, want killed by SIGKILL", status)
+	}
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Errorf("escalated after %v, before the grace period", elapsed)
+	}
+}
+
+func TestPidFD_TerminateTimeout(t *testing.T) {
+	_, pfd := startTerminateChild(t, "stubborn")
+
+	policy := iofd.TerminatePolicy{Steps: []iofd.TerminateStep{
+		{Signal: iofd.SIGTERM, Grace: 50 * time.Millisecond},
+	}}
+	if _, err := pfd.Terminate(context.Background(), policy); err != iofd.ErrTimeout {
+		t.Errorf("Terminate: expected ErrTimeout, got %v", err)
+	}
+}
+
+func TestPidFD_TerminateContext(t *testing.T) {
+	_, pfd := startTerminateChild(t, "stubborn")
+
+	policy := iofd.TerminatePolicy{Steps: []iofd.TerminateStep{{Signal: iofd.SIGTERM}}}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := pfd.Terminate(ctx, policy); err != context.DeadlineExceeded {
+		t.Errorf("Terminate: expected DeadlineExceeded, got %v", err)
+	}
+
+	// An already canceled context fails before sending any signal
+	if _, err := pfd.Terminate(ctx, iofd.TerminatePolicy{}); err != context.DeadlineExceeded {
+		t.Errorf("Terminate with done context: expected DeadlineExceeded, got %v", err)
+	}
+	if err := pfd.SendSignal(0); err != nil {
+		t.Errorf("process should still be alive: %v", err)
+	}
+}
+
+func TestPidFD_TerminateReleasesP(t *testing.T) {
+	_, pfd := startTerminateChild(t, "stubborn")
+
+	policy := iofd.TerminatePolicy{Steps: []iofd.TerminateStep{{Signal: iofd.SIGTERM}}}
+	ctx, cancel := context.WithCancel(context.Background())
+	checkReleasesP(t, func() error {
+		if _, err := pfd.Terminate(ctx, policy); err != context.Canceled {
+			return err
+		}
+		return nil
+	}, cancel)
+}
+
+func TestPidFD_TerminateReapedElsewhere(t *testing.T) {
+	cmd := helperCommand(t, "exit", "4")
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	pfd, err := iofd.NewPidFD(cmd.Process.Pid)
+	if err != nil {
+		t.Fatalf("NewPidFD failed: %v", err)
+	}
+	defer pfd.Close()
+	// Reap the child through os/exec so that waitid on the pidfd fails
+	_ = cmd.Wait()
+
+	status, err := pfd.Terminate(context.Background(), iofd.TerminatePolicy{})
+	if err == iofd.ErrProcessExited {
+		t.Skip("PIDFD_GET_INFO exit information not supported")
+	}
+	if err != nil {
+		t.Fatalf("Terminate failed: %v", err)
+	}
+	if status.ExitCode() != 4 {
+		t.Errorf("status = %v, want exit status 4", status)
+	}
+}
+
+func TestPidFD_TerminateErrors(t *testing.T) {
+	pfd, err := iofd.NewPidFD(os.Getpid())
+	if err != nil {
+		t.Fatalf("NewPidFD failed: %v", err)
+	}
+	invalid := []iofd.TerminatePolicy{
+		{Steps: []iofd.TerminateStep{{Signal: 0}}},
+		{Steps: []iofd.TerminateStep{{Signal: 65}}},
+		{Steps: []iofd.TerminateStep{{Signal: iofd.SIGTERM, Grace: -1}}},
+	}
+	for _, policy := range invalid {
+		if _, err := pfd.Terminate(context.Background(), policy); err != iofd.ErrInvalidParam {
+			t.Errorf("Terminate(%+v): expected ErrInvalidParam, got %v", policy, err)
+		}
+	}
+	pfd.Close()
+	if _, err := pfd.Terminate(context.Background(), iofd.TerminatePolicy{}); err != iofd.ErrClosed {
+		t.Errorf("Terminate on closed: expected ErrClosed, got %v", err)
+	}
+}
+
+// =============================================================================
+// Signal Mask Tests
+// =============================================================================
+
+func TestSignalMask_BlockUnblock(t *testing.T) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	orig, err := iofd.CurrentMask()
+	if err != nil {
+		t.Fatalf("CurrentMask failed: %v", err)
+	}
+	defer iofd.SetSignalMask(orig)
+
+	var set iofd.SigSet
+	set.Add(iofd.SIGUSR1)
+	set.Add(iofd.SIGWINCH)
+	old, err := iofd.BlockSignals(set)
+	if err != nil {
+		t.Fatalf("BlockSignals failed: %v", err)
+	}
+	if old != orig {
+		t.Errorf("BlockSignals returned %v, want previous mask %v", old, orig)
+	}
+	cur, _ := iofd.CurrentMask()
+	if !cur.Has(iofd.SIGUSR1) || !cur.Has(iofd.SIGWINCH) {
+		t.Errorf("mask %v should contain SIGUSR1 and SIGWINCH", cur)
+	}
+
+	var one iofd.SigSet
+	one.Add(iofd.SIGUSR1)
+	if _, err := iofd.UnblockSignals(one); err != nil {
+		t.Fatalf("UnblockSignals failed: %v", err)
+	}
+	cur, _ = iofd.CurrentMask()
+	if cur.Has(iofd.SIGUSR1) || !cur.Has(iofd.SIGWINCH) {
+		t.Errorf("mask %v should contain only SIGWINCH of the two", cur)
+	}
+
+	// SIGKILL and SIGSTOP cannot be blocked
+	var unblockable iofd.SigSet
+	unblockable.Add(iofd.SIGKILL)
+	unblockable.Add(iofd.SIGSTOP)
+	if _, err := iofd.BlockSignals(unblockable); err != nil {
+		t.Fatalf("BlockSignals failed: %v", err)
+	}
+	cur, _ = iofd.CurrentMask()
+	if cur.Has(iofd.SIGKILL) || cur.Has(iofd.SIGSTOP) {
+		t.Errorf("mask %v should not contain SIGKILL or SIGSTOP", cur)
+	}
+
+	if _, err := iofd.SetSignalMask(orig); err != nil {
+		t.Fatalf("SetSignalMask failed: %v", err)
+	}
+	if cur, _ = iofd.CurrentMask(); cur != orig {
+		t.Errorf("mask = %v after restore, want %v", cur, orig)
+	}
+}
+
+func TestSignalFDThread_Tgkill(t *testing.T) {
+	// Hold an outer lock so the thread can be inspected after Close
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	orig, _ := iofd.CurrentMask()
+
+	var mask iofd.SigSet
+	mask.Add(iofd.SIGUSR2)
+	sfd, err := iofd.NewSignalFDThread(mask)
+	if err != nil {
+		t.Fatalf("NewSignalFDThread failed: %v", err)
+	}
+	if sfd.TID() != syscall.Gettid() {
+		t.Errorf("TID() = %d, want %d", sfd.TID(), syscall.Gettid())
+	}
+	if cur, _ := iofd.CurrentMask(); !cur.Has(iofd.SIGUSR2) {
+		t.Errorf("mask %v should contain SIGUSR2", cur)
+	}
+
+	if err := syscall.Tgkill(os.Getpid(), sfd.TID(), syscall.SIGUSR2); err != nil {
+		t.Fatalf("Tgkill failed: %v", err)
+	}
+	info, err := sfd.Read()
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	if info.Signo != iofd.SIGUSR2 {
+		t.Errorf("Signo = %d, want SIGUSR2", info.Signo)
+	}
+	if info.Source() != iofd.SourceTKill {
+		t.Errorf("Source() = %v, want tkill", info.Source())
+	}
+	if int(info.PID) != os.Getpid() || int(info.UID) != os.Getuid() {
+		t.Errorf("sender = %d/%d, want %d/%d", info.PID, info.UID, os.Getpid(), os.Getuid())
+	}
+	if _, err := sfd.Read(); err != iox.ErrWouldBlock {
+		t.Errorf("second Read: expected ErrWouldBlock, got %v", err)
+	}
+
+	if err := sfd.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if cur, _ := iofd.CurrentMask(); cur != orig {
+		t.Errorf("mask = %v after Close, want %v", cur, orig)
+	}
+	if err := sfd.Close(); err != nil {
+		t.Errorf("second Close: %v", err)
+	}
+}
+
+func TestSignalFDThread_CloseWrongThread(t *testing.T) {
+	var mask iofd.SigSet
+	mask.Add(iofd.SIGUSR2)
+	created := make(chan *iofd.SignalFD)
+	release := make(chan struct{})
+	go func() {
+		sfd, err := iofd.NewSignalFDThread(mask)
+		if err != nil {
+			t.Errorf("NewSignalFDThread failed: %v", err)
+			close(created)
+			return
+		}
+		created <- sfd
+		// Exit while locked: the runtime discards the thread and its mask
+		<-release
+	}()
+	sfd := <-created
+	defer close(release)
+	if sfd == nil {
+		return
+	}
+
+	done := make(chan error)
+	go func() {
+		runtime.LockOSThread()
+		defer runtime.UnlockOSThread()
+		done <- sfd.Close()
+	}()
+	if err := <-done; err != iofd.ErrWrongThread {
+		t.Errorf("Close on another thread: expected ErrWrongThread, got %v", err)
+	}
+	if sfd.Fd() != -1 {
+		t.Errorf("descriptor should be closed, Fd() = %d", sfd.Fd())
+	}
+}
+
+// =============================================================================
+// SignalFD Batch Read Tests
+// =============================================================================
+
+// newThreadSignalFD creates a thread-bound signalfd for sigs on a thread
+// locked for the duration of the test.
+func newThreadSignalFD(t *testing.T, sigs ...int) *iofd.SignalFD {
+	t.Helper()
+	var mask iofd.SigSet
+	for _, sig := range sigs {
+		mask.Add(sig)
+	}
+	sfd, err := iofd.NewSignalFDThread(mask)
+	if err != nil {
+		t.Fatalf("NewSignalFDThread failed: %v", err)
+	}
+	t.Cleanup(func() { sfd.Close() })
+	return sfd
+}
+
+// tgkill sends sig to the thread the signalfd is bound to.
+func tgkill(t *testing.T, sfd *iofd.SignalFD, sig int) {
+	t.Helper()
+	if err := syscall.Tgkill(os.Getpid(), sfd.TID(), syscall.Signal(sig)); err != nil {
+		t.Fatalf("Tgkill(%d) failed: %v", sig, err)
+	}
+}
+
+func TestSignalFD_ReadBatch(t *testing.T) {
+	const rt = 40
+	sfd := newThreadSignalFD(t, iofd.SIGUSR1, iofd.SIGUSR2, rt)
+
+	dst := make([]iofd.SignalInfo, 8)
+	if _, err := sfd.ReadBatch(dst); err != iox.ErrWouldBlock {
+		t.Fatalf("ReadBatch with nothing pending: expected ErrWouldBlock, got %v", err)
+	}
+
+	// Real-time signals are queued, standard signals are not
+	tgkill(t, sfd, iofd.SIGUSR2)
+	tgkill(t, sfd, iofd.SIGUSR2)
+	tgkill(t, sfd, iofd.SIGUSR1)
+	tgkill(t, sfd, rt)
+	tgkill(t, sfd, rt)
+
+	n, err := sfd.ReadBatch(dst)
+	if err != nil {
+		t.Fatalf("ReadBatch failed: %v", err)
+	}
+	var got []uint32
+	for _, info := range dst[:n] {
+		got = append(got, info.Signo)
+	}
+	// Pending signals are dequeued lowest number first
+	want := []uint32{iofd.SIGUSR1, iofd.SIGUSR2, rt, rt}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("ReadBatch signals = %v, want %v", got, want)
+	}
+
+	// A short destination leaves the rest pending
+	tgkill(t, sfd, iofd.SIGUSR1)
+	tgkill(t, sfd, iofd.SIGUSR2)
+	if n, err := sfd.ReadBatch(dst[:1]); n != 1 || err != nil || dst[0].Signo != iofd.SIGUSR1 {
+		t.Errorf("ReadBatch(1) = (%d, %v) signo %d, want SIGUSR1", n, err, dst[0].Signo)
+	}
+	if n, err := sfd.ReadBatch(dst[:1]); n != 1 || err != nil || dst[0].Signo != iofd.SIGUSR2 {
+		t.Errorf("ReadBatch(1) = (%d, %v) signo %d, want SIGUSR2", n, err, dst[0].Signo)
+	}
+
+	if n, err := sfd.ReadBatch(nil); n != 0 || err != nil {
+		t.Errorf("ReadBatch(nil) = (%d, %v), want (0, nil)", n, err)
+	}
+}
+
+func TestSignalFD_ReadInfo(t *testing.T) {
+	sfd := newThreadSignalFD(t, iofd.SIGUSR1)
+
+	var info iofd.SignalInfo
+	tgkill(t, sfd, iofd.SIGUSR1)
+	if err := sfd.ReadInfo(&info); err != nil {
+		t.Fatalf("ReadInfo failed: %v", err)
+	}
+	if info.Signo != iofd.SIGUSR1 {
+		t.Errorf("ReadInfo: signo %d, want SIGUSR1", info.Signo)
+	}
+
+	if err := sfd.ReadInfo(&info); err != iox.ErrWouldBlock {
+		t.Errorf("ReadInfo without signal: expected ErrWouldBlock, got %v", err)
+	}
+	if err := sfd.ReadInfo(nil); err != iofd.ErrInvalidParam {
+		t.Errorf("ReadInfo(nil): expected ErrInvalidParam, got %v", err)
+	}
+}
+
+func TestSignalFD_ReadNoAllocs(t *testing.T) {
+	sfd := newThreadSignalFD(t, iofd.SIGUSR1)
+	pid, tid := os.Getpid(), sfd.TID()
+
+	var info iofd.SignalInfo
+	if allocs := testing.AllocsPerRun(100, func() {
+		_ = syscall.Tgkill(pid, tid, syscall.SIGUSR1)
+		_ = sfd.ReadInfo(&info)
+	}); allocs != 0 {
+		t.Errorf("ReadInfo allocates %v times per call", allocs)
+	}
+
+	batch := make([]iofd.SignalInfo, 4)
+	if allocs := testing.AllocsPerRun(100, func() {
+		_ = syscall.Tgkill(pid, tid, syscall.SIGUSR1)
+		_, _ = sfd.ReadBatch(batch)
+	}); allocs != 0 {
+		t.Errorf("ReadBatch allocates %v times per call", allocs)
+	}
+}
+
+func TestSignalFD_ReadBatchOnClosed(t *testing.T) {
+	sfd, err := iofd.NewSignalFD(0)
+	if err != nil {
+		t.Fatalf("NewSignalFD failed: %v", err)
+	}
+	sfd.Close()
+	if _, err := sfd.ReadBatch(make([]iofd.SignalInfo, 1)); err != iofd.ErrClosed {
+		t.Errorf("ReadBatch on closed: expected ErrClosed, got %v", err)
+	}
+}
+
+// =============================================================================
+// SignalInfo Decoding Tests
+// =============================================================================
+
+// Offsets of struct signalfd_siginfo fields as defined by the kernel ABI.
+const (
+	ssiSigno   = 0
+	ssiCode    = 8
+	ssiPID     = 12
+	ssiUID     = 16
+	ssiFD      = 20
+	ssiTID     = 24
+	ssiBand    = 28
+	ssiOverrun = 32
+	ssiTrapno  = 36
+	ssiStatus  = 40
+	ssiInt     = 44
+	ssiAddr    = 72
+	ssiAddrLsb = 80
+)
+
+// ssiField is a 32-bit field of a raw signalfd_siginfo record, or a 64-bit
+// one at ssiAddr and a 16-bit one at ssiAddrLsb.
+type ssiField struct {
+	off int
+	val int64
+}
+
+// rawSignalInfo builds a 128-byte signalfd_siginfo record from fields and
+// reinterprets it as a SignalInfo, as a read from a signalfd would.
+func rawSignalInfo(fields ...ssiField) *iofd.SignalInfo {
+	var buf [128]byte
+	for _, f := range fields {
+		switch f.off {
+		case ssiAddr:
+			binary.NativeEndian.PutUint64(buf[f.off:], uint64(f.val))
+		case ssiAddrLsb:
+			binary.NativeEndian.PutUint16(buf[f.off:], uint16(f.val))
+		default:
+			binary.NativeEndian.PutUint32(buf[f.off:], uint32(f.val))
+		}
+	}
+	info := new(iofd.SignalInfo)
+	*(*[128]byte)(unsafe.Pointer(info)) = buf
+	return info
+}
+
+func TestSignalInfo_Layout(t *testing.T) {
+	var info iofd.SignalInfo
+	offsets := []struct {
+		name string
+		got  uintptr
+		want uintptr
+	}{
+		{"Signo", unsafe.Offsetof(info.Signo), ssiSigno},
+		{"Code", unsafe.Offsetof(info.Code), ssiCode},
+		{"PID", unsafe.Offsetof(info.PID), ssiPID},
+		{"UID", unsafe.Offsetof(info.UID), ssiUID},
+		{"FD", unsafe.Offsetof(info.FD), ssiFD},
+		{"TID", unsafe.Offsetof(info.TID), ssiTID},
+		{"Band", unsafe.Offsetof(info.Band), ssiBand},
+		{"Overrun", unsafe.Offsetof(info.Overrun), ssiOverrun},
+		{"Trapno", unsafe.Offsetof(info.Trapno), ssiTrapno},
+		{"Status", unsafe.Offsetof(info.Status), ssiStatus},
+		{"Int", unsafe.Offsetof(info.Int), ssiInt},
+		{"Addr", unsafe.Offsetof(info.Addr), ssiAddr},
+		{"AddrLsb", unsafe.Offsetof(info.AddrLsb), ssiAddrLsb},
+	}
+	for _, o := range offsets {
+		if o.got != o.want {
+			t.Errorf("offset of %s = %d, want %d", o.name, o.got, o.want)
+		}
+	}
+	if size := unsafe.Sizeof(info); size != 128 {
+		t.Errorf("sizeof(SignalInfo) = %d, want 128", size)
+	}
+}
+
+func TestSignalInfo_Decode(t *testing.T) {
+	tests := []struct {
+		name   string
+		info   *iofd.SignalInfo
+		source iofd.SignalSource
+		child  string // ExitStatus.String, empty if not a child event
+		fault  string // SignalFault.String, empty if not a fault
+		str    string
+	}{
+		{
+			name: "kill",
+			info: rawSignalInfo(ssiField{ssiSigno, iofd.SIGTERM}, ssiField{ssiCode, iofd.SI_USER},
+				ssiField{ssiPID, 42}, ssiField{ssiUID, 1000}),
+			source: iofd.SourceUser,
+			str:    "SIGTERM from pid 42 uid 1000 (user)",
+		},
+		{
+			name: "tgkill",
+			info: rawSignalInfo(ssiField{ssiSigno, iofd.SIGUSR1}, ssiField{ssiCode, iofd.SI_TKILL},
+				ssiField{ssiPID, 7}, ssiField{ssiUID, 0}),
+			source: iofd.SourceTKill,
+			str:    "SIGUSR1 from pid 7 uid 0 (tkill)",
+		},
+		{
+			name: "sigqueue",
+			info: rawSignalInfo(ssiField{ssiSigno, iofd.SIGUSR2}, ssiField{ssiCode, iofd.SI_QUEUE},
+				ssiField{ssiPID, 9}, ssiField{ssiUID, 1}, ssiField{ssiInt, -5}),
+			source: iofd.SourceQueue,
+			str:    "SIGUSR2 from pid 9 uid 1 (queue) value -5",
+		},
+		{
+			name: "posix timer",
+			info: rawSignalInfo(ssiField{ssiSigno, iofd.SIGALRM}, ssiField{ssiCode, iofd.SI_TIMER},
+				ssiField{ssiTID, 3}, ssiField{ssiOverrun, 2}),
+			source: iofd.SourceTimer,
+			str:    "SIGALRM timer 3 overrun 2",
+		},
+		{
+			name:   "kernel",
+			info:   rawSignalInfo(ssiField{ssiSigno, iofd.SIGPIPE}, ssiField{ssiCode, iofd.SI_KERNEL}),
+			source: iofd.SourceKernel,
+			str:    "SIGPIPE (kernel)",
+		},
+		{
+			name: "sigio",
+			info: rawSignalInfo(ssiField{ssiSigno, iofd.SIGIO}, ssiField{ssiCode, 1}, // POLL_IN
+				ssiField{ssiFD, 5}, ssiField{ssiBand, 0x41}),
+			source: iofd.SourceKernel,
+			str:    "SIGIO fd 5 band 0x41",
+		},
+		{
+			name: "child exited",
+			info: rawSignalInfo(ssiField{ssiSigno, iofd.SIGCHLD}, ssiField{ssiCode, iofd.CLD_EXITED},
+				ssiField{ssiPID, 100}, ssiField{ssiStatus, 3}),
+			source: iofd.SourceKernel,
+			child:  "exit status 3",
+			str:    "SIGCHLD pid 100: exit status 3",
+		},
+		{
+			name: "child killed",
+			info: rawSignalInfo(ssiField{ssiSigno, iofd.SIGCHLD}, ssiField{ssiCode, iofd.CLD_KILLED},
+				ssiField{ssiPID, 101}, ssiField{ssiStatus, iofd.SIGKILL}),
+			source: iofd.SourceKernel,
+			child:  "signal: 9",
+			str:    "SIGCHLD pid 101: signal: 9",
+		},
+		{
+			name: "child dumped",
+			info: rawSignalInfo(ssiField{ssiSigno, iofd.SIGCHLD}, ssiField{ssiCode, iofd.CLD_DUMPED},
+				ssiField{ssiPID, 102}, ssiField{ssiStatus, iofd.SIGSEGV}),
+			source: iofd.SourceKernel,
+			child:  "signal: 11 (core dumped)",
+			str:    "SIGCHLD pid 102: signal: 11 (core dumped)",
+		},
+		{
+			name: "child stopped",
+			info: rawSignalInfo(ssiField{ssiSigno, iofd.SIGCHLD}, ssiField{ssiCode, iofd.CLD_STOPPED},
+				ssiField{ssiPID, 103}, ssiField{ssiStatus, iofd.SIGSTOP}),
+			source: iofd.SourceKernel,
+			child:  "stop signal: 19",
+			str:    "SIGCHLD pid 103: stop signal: 19",
+		},
+		{
+			name: "sigchld from kill",
+			info: rawSignalInfo(ssiField{ssiSigno, iofd.SIGCHLD}, ssiField{ssiCode, iofd.SI_USER},
+				ssiField{ssiPID, 104}),
+			source: iofd.SourceUser,
+			str:    "SIGCHLD from pid 104 uid 0 (user)",
+		},
+		{
+			name: "segv maperr",
+			info: rawSignalInfo(ssiField{ssiSigno, iofd.SIGSEGV}, ssiField{ssiCode, iofd.SEGV_MAPERR},
+				ssiField{ssiAddr, 0xdeadbeef}),
+			source: iofd.SourceKernel,
+			fault:  "SEGV_MAPERR addr=0xdeadbeef",
+			str:    "SIGSEGV SEGV_MAPERR addr=0xdeadbeef",
+		},
+		{
+			name: "segv accerr",
+			info: rawSignalInfo(ssiField{ssiSigno, iofd.SIGSEGV}, ssiField{ssiCode, iofd.SEGV_ACCERR},
+				ssiField{ssiAddr, 0x1000}),
+			source: iofd.SourceKernel,
+			fault:  "SEGV_ACCERR addr=0x1000",
+			str:    "SIGSEGV SEGV_ACCERR addr=0x1000",
+		},
+		{
+			name: "bus adraln",
+			info: rawSignalInfo(ssiField{ssiSigno, iofd.SIGBUS}, ssiField{ssiCode, iofd.BUS_ADRALN},
+				ssiField{ssiAddr, 0x7}),
+			source: iofd.SourceKernel,
+			fault:  "BUS_ADRALN addr=0x7",
+			str:    "SIGBUS BUS_ADRALN addr=0x7",
+		},
+		{
+			name: "fpe intdiv",
+			info: rawSignalInfo(ssiField{ssiSigno, iofd.SIGFPE}, ssiField{ssiCode, iofd.FPE_INTDIV},
+				ssiField{ssiAddr, 0x401000}),
+			source: iofd.SourceKernel,
+			fault:  "FPE_INTDIV addr=0x401000",
+			str:    "SIGFPE FPE_INTDIV addr=0x401000",
+		},
+		{
+			name:   "unknown fault code",
+			info:   rawSignalInfo(ssiField{ssiSigno, iofd.SIGILL}, ssiField{ssiCode, 42}),
+			source: iofd.SourceKernel,
+			fault:  "code 42 addr=0x0",
+			str:    "SIGILL code 42 addr=0x0",
+		},
+		{
+			name: "segv from kill",
+			info: rawSignalInfo(ssiField{ssiSigno, iofd.SIGSEGV}, ssiField{ssiCode, iofd.SI_USER},
+				ssiField{ssiPID, 5}),
+			source: iofd.SourceUser,
+			str:    "SIGSEGV from pid 5 uid 0 (user)",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.info.Source(); got != tt.source {
+				t.Errorf("Source() = %v, want %v", got, tt.source)
+			}
+			st, ok := tt.info.ChildEvent()
+			if ok != (tt.child != "") || (ok && st.String() != tt.child) {
+				t.Errorf("ChildEvent() = (%v, %v), want %q", st, ok, tt.child)
+			}
+			f, ok := tt.info.Fault()
+			if ok != (tt.fault != "") || (ok && f.String() != tt.fault) {
+				t.Errorf("Fault() = (%v, %v), want %q", f, ok, tt.fault)
+			}
+			if got := tt.info.String(); got != tt.str {
+				t.Errorf("String() = %q, want %q", got, tt.str)
+			}
+		})
+	}
+}
+
+func TestSignalInfo_FaultFields(t *testing.T) {
+	info := rawSignalInfo(ssiField{ssiSigno, iofd.SIGBUS}, ssiField{ssiCode, iofd.BUS_MCEERR_AR},
+		ssiField{ssiAddr, 0x200000}, ssiField{ssiAddrLsb, 12}, ssiField{ssiTrapno, 18})
+	f, ok := info.Fault()
+	if !ok {
+		t.Fatal("Fault() reported no fault")
+	}
+	want := iofd.SignalFault{Signal: iofd.SIGBUS, Code: iofd.BUS_MCEERR_AR, Addr: 0x200000, AddrLsb: 12, Trapno: 18}
+	if f != want {
+		t.Errorf("Fault() = %+v, want %+v", f, want)
+	}
+}
+
+// =============================================================================
+// Real-Time Signal and SigSet Tests
+// =============================================================================
+
+func TestSIGRTMINMAX(t *testing.T) {
+	if min := iofd.SIGRTMIN(); min != 34 && min != 35 {
+		t.Errorf("SIGRTMIN() = %d, want 34 or 35", min)
+	}
+	if max := iofd.SIGRTMAX(); max != 64 {
+		t.Errorf("SIGRTMAX() = %d, want 64", max)
+	}
+}
+
+func TestSigSet_Operations(t *testing.T) {
+	a := iofd.SigSetOf(iofd.SIGINT, iofd.SIGTERM, 0, 65)
+	b := iofd.SigSetOf(iofd.SIGTERM, iofd.SIGHUP)
+
+	if got := slices.Collect(a.Signals()); !slices.Equal(got, []int{iofd.SIGINT, iofd.SIGTERM}) {
+		t.Errorf("SigSetOf ignoring out-of-range signals: got %v", got)
+	}
+	if got := slices.Collect(a.Union(b).Signals()); !slices.Equal(got, []int{iofd.SIGHUP, iofd.SIGINT, iofd.SIGTERM}) {
+		t.Errorf("Union = %v", got)
+	}
+	if got := slices.Collect(a.Intersect(b).Signals()); !slices.Equal(got, []int{iofd.SIGTERM}) {
+		t.Errorf("Intersect = %v", got)
+	}
+	c := a.Complement()
+	if c.Has(iofd.SIGINT) || c.Has(iofd.SIGTERM) || !c.Has(iofd.SIGHUP) || !c.Has(64) {
+		t.Errorf("Complement = %v", c)
+	}
+	if n := len(slices.Collect(c.Signals())); n != 62 {
+		t.Errorf("Complement has %d signals, want 62", n)
+	}
+	if !a.Intersect(c).Empty() || a.Union(c).Complement() != 0 {
+		t.Error("set and complement should be disjoint and cover all signals")
+	}
+
+	// Iteration stops early
+	var first []int
+	for sig := range b.Union(a).Signals() {
+		first = append(first, sig)
+		if len(first) == 2 {
+			break
+		}
+	}
+	if !slices.Equal(first, []int{iofd.SIGHUP, iofd.SIGINT}) {
+		t.Errorf("early break collected %v", first)
+	}
+}
+
+func TestSigSet_String(t *testing.T) {
+	rtmin := iofd.SIGRTMIN()
+	tests := []struct {
+		set  iofd.SigSet
+		want string
+	}{
+		{0, "{}"},
+		{iofd.SigSetOf(iofd.SIGTERM), "{SIGTERM}"},
+		{iofd.SigSetOf(iofd.SIGTERM, iofd.SIGINT), "{SIGINT, SIGTERM}"},
+		{iofd.SigSetOf(32, rtmin, rtmin+3, 64), "{SIG32, SIGRTMIN, SIGRTMIN+3, SIGRTMAX}"},
+	}
+	for _, tt := range tests {
+		if got := tt.set.String(); got != tt.want {
+			t.Errorf("%#x.String() = %q, want %q", uint64(tt.set), got, tt.want)
+		}
+		if got := fmt.Sprintf("%v", tt.set); got != tt.want {
+			t.Errorf("%%v of %#x = %q, want %q", uint64(tt.set), got, tt.want)
+		}
+		// Numeric verbs keep printing the raw mask
+		if got, want := fmt.Sprintf("%x", tt.set), strconv.FormatUint(uint64(tt.set), 16); got != want {
+			t.Errorf("%%x of %#x = %q, want %q", uint64(tt.set), got, want)
+		}
+		if got, want := fmt.Sprintf("%#016x", tt.set), fmt.Sprintf("%#016x", uint64(tt.set)); got != want {
+			t.Errorf("%%#016x of %#x = %q, want %q", uint64(tt.set), got, want)
+		}
+		if got, want := fmt.Sprintf("%d", tt.set), strconv.FormatUint(uint64(tt.set), 10); got != want {
+			t.Errorf("%%d of %#x = %q, want %q", uint64(tt.set), got, want)
+		}
+	}
+}
+
+func TestParseSignal(t *testing.T) {
+	rtmin := iofd.SIGRTMIN()
+	tests := []struct {
+		in   string
+		want int
+	}{
+		{"SIGTERM", iofd.SIGTERM},
+		{"TERM", iofd.SIGTERM},
+		{"sigterm", iofd.SIGTERM},
+		{" hup ", iofd.SIGHUP},
+		{"15", iofd.SIGTERM},
+		{"SIGIOT", iofd.SIGABRT},
+		{"CLD", iofd.SIGCHLD},
+		{"SIGPOLL", iofd.SIGIO},
+		{"RTMIN", rtmin},
+		{"RTMIN+3", rtmin + 3},
+		{"SIGRTMIN+0", rtmin},
+		{"SIGRTMAX", 64},
+		{"RTMAX-2", 62},
+		{"SIG32", 32},
+		{"SIG33", 33},
+	}
+	for _, tt := range tests {
+		got, err := iofd.ParseSignal(tt.in)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseSignal(%q) = (%d, %v), want %d", tt.in, got, err, tt.want)
+		}
+	}
+
+	invalid := []string{"", "SIG", "FOO", "0", "65", "-1", "RTMIN-1", "RTMAX+1", "RTMIN+x", "RTMIN++3",
+		"RTMIN+31", "SIG31", "SIG65"}
+	for _, in := range invalid {
+		if got, err := iofd.ParseSignal(in); !errors.Is(err, iofd.ErrInvalidParam) {
+			t.Errorf("ParseSignal(%q) = (%d, %v), want ErrInvalidParam", in, got, err)
+		}
+	}
+
+	// Every name round-trips
+	for sig := 1; sig <= 64; sig++ {
+		name := iofd.SignalName(sig)
+		if got, err := iofd.ParseSignal(name); err != nil || got != sig {
+			t.Errorf("ParseSignal(SignalName(%d) = %q) = (%d, %v)", sig, name, got, err)
+		}
+	}
+	if name := iofd.SignalName(0); name != "signal 0" {
+		t.Errorf("SignalName(0) = %q", name)
+	}
+}
+
+func TestSignalFD_RealtimeOrdering(t *testing.T) {
+	rtmin := iofd.SIGRTMIN()
+	sfd := newThreadSignalFD(t, iofd.SIGUSR1, rtmin+1, rtmin+3)
+
+	// Real-time signals are queued per instance and delivered lowest
+	// number first after standard signals
+	tgkill(t, sfd, rtmin+3)
+	tgkill(t, sfd, rtmin+1)
+	tgkill(t, sfd, rtmin+3)
+	tgkill(t, sfd, rtmin+1)
+	tgkill(t, sfd, iofd.SIGUSR1)
+
+	dst := make([]iofd.SignalInfo, 8)
+	n, err := sfd.ReadBatch(dst)
+	if err != nil {
+		t.Fatalf("ReadBatch failed: %v", err)
+	}
+	var got []string
+	for _, info := range dst[:n] {
+		got = append(got, iofd.SignalName(int(info.Signo)))
+	}
+	want := []string{"SIGUSR1", "SIGRTMIN+1", "SIGRTMIN+1", "SIGRTMIN+3", "SIGRTMIN+3"}
+	if !slices.Equal(got, want) {
+		t.Errorf("delivery order = %v, want %v", got, want)
+	}
+}
+
+// =============================================================================
+// SignalFD os/signal Interoperability Tests
+// =============================================================================
+
+func TestSignalFDInterop_NotBlocked(t *testing.T) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	mask := iofd.SigSetOf(iofd.SIGUSR1, iofd.SIGUSR2)
+	old, _ := iofd.BlockSignals(iofd.SigSetOf(iofd.SIGUSR2))
+	defer iofd.SetSignalMask(old)
+
+	if _, err := iofd.NewSignalFDInterop(mask); err != iofd.ErrSignalNotBlocked {
+		t.Errorf("expected ErrSignalNotBlocked, got %v", err)
+	}
+}
+
+func TestSignalFDInterop_RuntimeSignals(t *testing.T) {
+	for _, sig := range []int{iofd.SIGKILL, iofd.SIGSTOP, iofd.SIGURG, 32, 33} {
+		if _, err := iofd.NewSignalFDInterop(iofd.SigSetOf(iofd.SIGUSR1, sig)); err != iofd.ErrInvalidParam {
+			t.Errorf("mask with %s: expected ErrInvalidParam, got %v", iofd.SignalName(sig), err)
+		}
+	}
+}
+
+func TestSignalFDInterop_WithNotify(t *testing.T) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, syscall.SIGUSR2)
+	defer signal.Stop(ch)
+
+	mask := iofd.SigSetOf(iofd.SIGUSR2)
+	old, _ := iofd.BlockSignals(mask)
+	defer iofd.SetSignalMask(old)
+
+	sfd, err := iofd.NewSignalFDInterop(mask)
+	if err != nil {
+		t.Fatalf("NewSignalFDInterop failed: %v", err)
+	}
+	defer sfd.Close()
+
+	if err := syscall.Tgkill(os.Getpid(), syscall.Gettid(), syscall.SIGUSR2); err != nil {
+		t.Fatalf("Tgkill failed: %v", err)
+	}
+	var info iofd.SignalInfo
+	if err := sfd.ReadInfo(&info); err != nil || info.Signo != iofd.SIGUSR2 {
+		t.Fatalf("ReadInfo = (%v, %v), want SIGUSR2", &info, err)
+	}
+	select {
+	case sig := <-ch:
+		t.Errorf("os/signal channel also received thread signal %v", sig)
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+func TestSignalFDInterop_ProcessSignal(t *testing.T) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	mask := iofd.SigSetOf(iofd.SIGUSR1)
+	old, _ := iofd.BlockSignals(mask)
+	defer iofd.SetSignalMask(old)
+
+	sfd, err := iofd.NewSignalFDInterop(mask)
+	if err != nil {
+		t.Fatalf("NewSignalFDInterop failed: %v", err)
+	}
+	// A signal sent to the process is taken by another thread and
+	// forwarded to this one
+	if err := syscall.Kill(os.Getpid(), syscall.SIGUSR1); err != nil {
+		t.Fatalf("Kill failed: %v", err)
+	}
+	if !epollWaitReadable(t, sfd.Fd(), 5*time.Second) {
+		t.Fatal("process-directed signal not forwarded to the signalfd")
+	}
+	var info iofd.SignalInfo
+	if err := sfd.ReadInfo(&info); err != nil || info.Signo != iofd.SIGUSR1 {
+		t.Fatalf("ReadInfo = (%v, %v), want SIGUSR1", &info, err)
+	}
+	if err := sfd.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	// After Close, the signal is handed back to os/signal
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, syscall.SIGUSR1)
+	defer signal.Stop(ch)
+	if err := syscall.Kill(os.Getpid(), syscall.SIGUSR1); err != nil {
+		t.Fatalf("Kill failed: %v", err)
+	}
+	select {
+	case <-ch:
+	case <-time.After(5 * time.Second):
+		t.Error("os/signal did not receive the signal after Close")
+	}
+}
+
+func TestSignalFDInterop_Ignored(t *testing.T) {
+	// SIGHUP ignored at exec stays ignored in a Go program
+	signal.Ignore(syscall.SIGHUP)
+	cmd := helperCommand(t, "interop-ignored")
+	out, err := cmd.Output()
+	signal.Reset(syscall.SIGHUP)
+	if err != nil {
+		t.Fatalf("helper failed: %v", err)
+	}
+	if msg := strings.TrimSpace(string(out)); msg != iofd.ErrSignalIgnored.Error() {
+		t.Errorf("helper reported %q, want ErrSignalIgnored", msg)
+	}
+}
+
+// =============================================================================
+// SignalDispatcher Tests
+// =============================================================================
+
+// newTestDispatcher creates a dispatcher on a thread locked for the
+// duration of the test, with sigs blocked on that thread.
+func newTestDispatcher(t *testing.T, sigs ...int) *iofd.SignalDispatcher {
+	t.Helper()
+	runtime.LockOSThread()
+	old, err := iofd.BlockSignals(iofd.SigSetOf(sigs...))
+	if err != nil {
+		t.Fatalf("BlockSignals failed: %v", err)
+	}
+	d, err := iofd.NewSignalDispatcher()
+	if err != nil {
+		t.Fatalf("NewSignalDispatcher failed: %v", err)
+	}
+	t.Cleanup(func() {
+		d.Close()
+		iofd.SetSignalMask(old)
+		runtime.UnlockOSThread()
+	})
+	return d
+}
+
+// raise sends sig to the calling thread.
+func raise(t *testing.T, sig int) {
+	t.Helper()
+	if err := syscall.Tgkill(os.Getpid(), syscall.Gettid(), syscall.Signal(sig)); err != nil {
+		t.Fatalf("Tgkill(%d) failed: %v", sig, err)
+	}
+}
+
+func TestSignalDispatcher_Dispatch(t *testing.T) {
+	rt := iofd.SIGRTMIN() + 2
+	d := newTestDispatcher(t, iofd.SIGUSR1, iofd.SIGUSR2, rt)
+
+	type call struct {
+		sig int
+		n   int
+	}
+	var calls []call
+	record := func(info *iofd.SignalInfo, n int) {
+		calls = append(calls, call{int(info.Signo), n})
+	}
+	for _, sig := range []int{iofd.SIGUSR2, rt, iofd.SIGUSR1} {
+		if err := d.Handle(sig, record); err != nil {
+			t.Fatalf("Handle(%d) failed: %v", sig, err)
+		}
+	}
+	if want := iofd.SigSetOf(iofd.SIGUSR1, iofd.SIGUSR2, rt); d.Mask() != want {
+		t.Errorf("Mask() = %v, want %v", d.Mask(), want)
+	}
+
+	if _, err := d.Dispatch(); err != iox.ErrWouldBlock {
+		t.Fatalf("Dispatch with nothing pending: expected ErrWouldBlock, got %v", err)
+	}
+
+	// A burst of queued real-time signals is coalesced into one call
+	raise(t, rt)
+	raise(t, rt)
+	raise(t, rt)
+	raise(t, iofd.SIGUSR2)
+	raise(t, iofd.SIGUSR1)
+	if !epollWaitReadable(t, d.Fd(), time.Second) {
+		t.Fatal("dispatcher fd not readable")
+	}
+	n, err := d.Dispatch()
+	if err != nil || n != 3 {
+		t.Fatalf("Dispatch = (%d, %v), want 3 handler calls", n, err)
+	}
+	want := []call{{iofd.SIGUSR1, 1}, {iofd.SIGUSR2, 1}, {rt, 3}}
+	if !slices.Equal(calls, want) {
+		t.Errorf("calls = %v, want %v", calls, want)
+	}
+
+	// Removing a handler removes the signal from the mask
+	if err := d.Handle(iofd.SIGUSR2, nil); err != nil {
+		t.Fatalf("Handle(nil) failed: %v", err)
+	}
+	if d.Mask().Has(iofd.SIGUSR2) {
+		t.Errorf("Mask() = %v still contains SIGUSR2", d.Mask())
+	}
+	calls = nil
+	raise(t, iofd.SIGUSR2)
+	if _, err := d.Dispatch(); err != iox.ErrWouldBlock {
+		t.Errorf("Dispatch of unhandled signal: expected ErrWouldBlock, got %v", err)
+	}
+	// Drain the blocked SIGUSR2 so it does not leak into other tests
+	sfd := newThreadSignalFD(t, iofd.SIGUSR2)
+	if _, err := sfd.Read(); err != nil {
+		t.Errorf("SIGUSR2 should stay pending: %v", err)
+	}
+}
+
+func TestSignalDispatcher_HandleFromHandler(t *testing.T) {
+	d := newTestDispatcher(t, iofd.SIGUSR1)
+
+	// A one-shot handler that unregisters itself
+	calls := 0
+	err := d.Handle(iofd.SIGUSR1, func(*iofd.SignalInfo, int) {
+		calls++
+		if err := d.Handle(iofd.SIGUSR1, nil); err != nil {
+			t.Errorf("Handle from handler failed: %v", err)
+		}
+	})
+	if err != nil {
+		t.Fatalf("Handle failed: %v", err)
+	}
+	raise(t, iofd.SIGUSR1)
+	if n, err := d.Dispatch(); n != 1 || err != nil {
+		t.Fatalf("Dispatch = (%d, %v)", n, err)
+	}
+	if calls != 1 || !d.Mask().Empty() {
+		t.Errorf("calls = %d, Mask() = %v; want 1 call and empty mask", calls, d.Mask())
+	}
+}
+
+func TestSignalDispatcher_Run(t *testing.T) {
+	d := newTestDispatcher(t, iofd.SIGUSR1, iofd.SIGUSR2)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var got []int
+	d.Handle(iofd.SIGUSR1, func(info *iofd.SignalInfo, n int) {
+		got = append(got, int(info.Signo))
+		// Signal more work while running, then stop
+		raise(t, iofd.SIGUSR2)
+	})
+	d.Handle(iofd.SIGUSR2, func(info *iofd.SignalInfo, n int) {
+		got = append(got, int(info.Signo))
+		cancel()
+	})
+
+	raise(t, iofd.SIGUSR1)
+	if err := d.Run(ctx); err != context.Canceled {
+		t.Errorf("Run: expected Canceled, got %v", err)
+	}
+	if !slices.Equal(got, []int{iofd.SIGUSR1, iofd.SIGUSR2}) {
+		t.Errorf("handled %v, want [SIGUSR1 SIGUSR2]", got)
+	}
+
+	// Run returns when the context expires while idle
+	ctx, cancel2 := context.WithTimeout(context.Background(), 30*time.Millisecond)
+	defer cancel2()
+	if err := d.Run(ctx); err != context.DeadlineExceeded {
+		t.Errorf("Run: expected DeadlineExceeded, got %v", err)
+	}
+}
+
+func TestSignalDispatcher_RunReleasesP(t *testing.T) {
+	d := newTestDispatcher(t, iofd.SIGUSR1)
+	d.Handle(iofd.SIGUSR1, func(*iofd.SignalInfo, int) {})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	checkReleasesP(t, func() error {
+		if err := d.Run(ctx); err != context.Canceled {
+			return err
+		}
+		return nil
+	}, cancel)
+}
+
+func TestSignalDispatcher_Errors(t *testing.T) {
+	d, err := iofd.NewSignalDispatcher()
+	if err != nil {
+		t.Fatalf("NewSignalDispatcher failed: %v", err)
+	}
+	for _, sig := range []int{0, 65, iofd.SIGKILL, iofd.SIGSTOP, iofd.SIGURG} {
+		if err := d.Handle(sig, func(*iofd.SignalInfo, int) {}); err != iofd.ErrInvalidParam {
+			t.Errorf("Handle(%d): expected ErrInvalidParam, got %v", sig, err)
+		}
+	}
+	d.Close()
+	if _, err := d.Dispatch(); err != iofd.ErrClosed {
+		t.Errorf("Dispatch on closed: expected ErrClosed, got %v", err)
+	}
+	if err := d.Run(context.Background()); err != iofd.ErrClosed {
+		t.Errorf("Run on closed: expected ErrClosed, got %v", err)
+	}
+	if err := d.Handle(iofd.SIGUSR1, func(*iofd.SignalInfo, int) {}); err != iofd.ErrClosed {
+		t.Errorf("Handle on closed: expected ErrClosed, got %v", err)
+	}
+}
+
+// =============================================================================
+// PosixTimer Tests
+// =============================================================================
+
+// readSignal waits for the signalfd to become readable and reads one signal.
+func readSignal(t *testing.T, sfd *iofd.SignalFD, timeout time.Duration) *iofd.SignalInfo {
+	t.Helper()
+	if !epollWaitReadable(t, sfd.Fd(), timeout) {
+		t.Fatal("no signal received before timeout")
+	}
+	info, err := sfd.Read()
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	return info
+}
+
+func TestPosixTimer_Expiration(t *testing.T) {
+	sig := iofd.SIGRTMIN() + 4
+	sfd := newThreadSignalFD(t, sig)
+
+	pt, err := iofd.NewPosixTimer(iofd.CLOCK_MONOTONIC, sig, 0)
+	if err != nil {
+		t.Fatalf("NewPosixTimer failed: %v", err)
+	}
+	defer pt.Close()
+	if pt.TID() != sfd.TID() || pt.Signal() != sig || pt.ID() < 0 {
+		t.Errorf("timer targets tid %d signal %d id %d", pt.TID(), pt.Signal(), pt.ID())
+	}
+
+	if err := pt.Arm(int64(5*time.Millisecond), 0); err != nil {
+		t.Fatalf("Arm failed: %v", err)
+	}
+	info := readSignal(t, sfd, time.Second)
+	if info.Source() != iofd.SourceTimer || int(info.TID) != pt.ID() {
+		t.Errorf("got %v, want a timer signal from timer %d", info, pt.ID())
+	}
+	if n, ok := pt.Expirations(info); !ok || n != 1 {
+		t.Errorf("Expirations = (%d, %v), want (1, true)", n, ok)
+	}
+	if remaining, interval, err := pt.GetTime(); err != nil || remaining != 0 || interval != 0 {
+		t.Errorf("GetTime after one-shot = (%d, %d, %v), want disarmed", remaining, interval, err)
+	}
+
+	// Signals from other sources are not expirations
+	other := *info
+	other.Code = iofd.SI_TKILL
+	if _, ok := pt.Expirations(&other); ok {
+		t.Error("tgkill signal decoded as expiration")
+	}
+}
+
+func TestPosixTimer_Overrun(t *testing.T) {
+	sig := iofd.SIGRTMIN() + 5
+	sfd := newThreadSignalFD(t, sig)
+
+	pt, err := iofd.NewPosixTimer(iofd.CLOCK_MONOTONIC, sig, 0)
+	if err != nil {
+		t.Fatalf("NewPosixTimer failed: %v", err)
+	}
+	defer pt.Close()
+
+	interval := int64(time.Millisecond)
+	if err := pt.Arm(interval, interval); err != nil {
+		t.Fatalf("Arm failed: %v", err)
+	}
+	if _, iv, err := pt.GetTime(); err != nil || iv != interval {
+		t.Errorf("GetTime interval = (%d, %v), want %d", iv, err, interval)
+	}
+	// Expirations while the signal is pending are counted as overruns
+	time.Sleep(30 * time.Millisecond)
+	info := readSignal(t, sfd, time.Second)
+	n, ok := pt.Expirations(info)
+	if !ok || n < 2 {
+		t.Errorf("Expirations = (%d, %v), want overruns after 30 intervals", n, ok)
+	}
+	if overrun, err := pt.Overrun(); err != nil || uint64(overrun) != n-1 {
+		t.Errorf("Overrun() = (%d, %v), want %d", overrun, err, n-1)
+	}
+
+	if err := pt.Disarm(); err != nil {
+		t.Fatalf("Disarm failed: %v", err)
+	}
+	// Drain an expiration that may have been queued before Disarm
+	_, _ = sfd.Read()
+	time.Sleep(5 * time.Millisecond)
+	if _, err := sfd.Read(); err != iox.ErrWouldBlock {
+		t.Errorf("Read after Disarm: expected ErrWouldBlock, got %v", err)
+	}
+}
+
+func TestPosixTimer_ThreadCPUClock(t *testing.T) {
+	sig := iofd.SIGRTMIN() + 6
+	sfd := newThreadSignalFD(t, sig)
+
+	// The thread CPU clock of the calling thread advances only while it runs
+	pt, err := iofd.NewPosixTimer(iofd.CLOCK_THREAD_CPUTIME_ID, sig, 0)
+	if err != nil {
+		t.Fatalf("NewPosixTimer failed: %v", err)
+	}
+	defer pt.Close()
+	if err := pt.Arm(int64(10*time.Millisecond), 0); err != nil {
+		t.Fatalf("Arm failed: %v", err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	var info iofd.SignalInfo
+	for time.Now().Before(deadline) {
+		if err := sfd.ReadInfo(&info); err == nil {
+			break
+		}
+	}
+	if n, ok := pt.Expirations(&info); !ok || n != 1 {
+		t.Errorf("Expirations = (%d, %v) after spinning, want (1, true)", n, ok)
+	}
+}
+
+func TestPosixTimer_Errors(t *testing.T) {
+	if _, err := iofd.NewPosixTimer(iofd.CLOCK_MONOTONIC, 0, 0); err != iofd.ErrInvalidParam {
+		t.Errorf("signal 0: expected ErrInvalidParam, got %v", err)
+	}
+	if _, err := iofd.NewPosixTimer(iofd.CLOCK_MONOTONIC, iofd.SIGUSR1, -1); err != iofd.ErrInvalidParam {
+		t.Errorf("tid -1: expected ErrInvalidParam, got %v", err)
+	}
+	if _, err := iofd.NewPosixTimer(99, iofd.SIGUSR1, 0); err != iofd.ErrInvalidParam {
+		t.Errorf("bad clock: expected ErrInvalidParam, got %v", err)
+	}
+
+	pt, err := iofd.NewPosixTimer(iofd.CLOCK_MONOTONIC, iofd.SIGUSR1, 0)
+	if err != nil {
+		t.Fatalf("NewPosixTimer failed: %v", err)
+	}
+	if err := pt.Arm(-1, 0); err != iofd.ErrInvalidParam {
+		t.Errorf("negative Arm: expected ErrInvalidParam, got %v", err)
+	}
+	if err := pt.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if err := pt.Close(); err != nil {
+		t.Errorf("second Close: %v", err)
+	}
+	if pt.ID() != -1 {
+		t.Errorf("ID() after Close = %d, want -1", pt.ID())
+	}
+	if err := pt.Arm(1, 0); err != iofd.ErrClosed {
+		t.Errorf("Arm on closed: expected ErrClosed, got %v", err)
+	}
+	if _, _, err := pt.GetTime(); err != iofd.ErrClosed {
+		t.Errorf("GetTime on closed: expected ErrClosed, got %v", err)
+	}
+	if _, err := pt.Overrun(); err != iofd.ErrClosed {
+		t.Errorf("Overrun on closed: expected ErrClosed, got %v", err)
+	}
+}
+
+// =============================================================================
+// CPUBudget Tests
+// =============================================================================
+
+// spinUntilExhausted burns CPU on the calling thread and checks b whenever
+// its timerfd becomes readable. Returns the number of checks that found
+// the budget not yet exhausted.
+func spinUntilExhausted(t *testing.T, b *iofd.CPUBudget, timeout time.Duration) int {
+	t.Helper()
+	early := 0
+	for deadline := time.Now().Add(timeout); time.Now().Before(deadline); {
+		for spin := time.Now(); time.Since(spin) < time.Millisecond; {
+		}
+		if !epollWaitReadable(t, b.Fd(), 0) {
+			continue
+		}
+		exhausted, err := b.Check()
+		if err != nil {
+			t.Fatalf("Check failed: %v", err)
+		}
+		if exhausted {
+			return early
+		}
+		early++
+	}
+	t.Fatal("budget not exhausted before timeout")
+	return early
+}
+
+func TestCPUBudget_Thread(t *testing.T) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	const budget = 20 * time.Millisecond
+	b, err := iofd.NewThreadCPUBudget(budget)
+	if err != nil {
+		t.Fatalf("NewThreadCPUBudget failed: %v", err)
+	}
+	defer b.Close()
+	if b.Budget() != budget {
+		t.Errorf("Budget = %v, want %v", b.Budget(), budget)
+	}
+
+	spinUntilExhausted(t, b, 5*time.Second)
+	used, err := b.Used()
+	if err != nil {
+		t.Fatalf("Used failed: %v", err)
+	}
+	if used < budget {
+		t.Errorf("exhausted after %v, want at least %v", used, budget)
+	}
+	// The timer stays disarmed once exhausted
+	if epollWaitReadable(t, b.Fd(), 20*time.Millisecond) {
+		t.Error("timerfd readable after exhaustion")
+	}
+	if exhausted, err := b.Check(); err != nil || !exhausted {
+		t.Errorf("Check after exhaustion = (%v, %v), want (true, nil)", exhausted, err)
+	}
+
+	// Reset starts a new budget from the current CPU time
+	if err := b.Reset(time.Hour); err != nil {
+		t.Fatalf("Reset failed: %v", err)
+	}
+	if exhausted, err := b.Check(); err != nil || exhausted {
+		t.Errorf("Check after Reset = (%v, %v), want (false, nil)", exhausted, err)
+	}
+}
+
+func TestCPUBudget_IdleThread(t *testing.T) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	b, err := iofd.NewThreadCPUBudget(5 * time.Millisecond)
+	if err != nil {
+		t.Fatalf("NewThreadCPUBudget failed: %v", err)
+	}
+	defer b.Close()
+
+	// The timer fires after the budget in wall time, but a sleeping thread
+	// has not consumed it
+	if !epollWaitReadable(t, b.Fd(), time.Second) {
+		t.Fatal("timerfd not readable")
+	}
+	exhausted, err := b.Check()
+	if err != nil || exhausted {
+		t.Errorf("Check = (%v, %v), want (false, nil)", exhausted, err)
+	}
+	if used, err := b.Used(); err != nil || used >= 5*time.Millisecond {
+		t.Errorf("Used = (%v, %v), want less than the budget", used, err)
+	}
+}
+
+func TestCPUBudget_Process(t *testing.T) {
+	b, err := iofd.NewProcessCPUBudget(20 * time.Millisecond)
+	if err != nil {
+		t.Fatalf("NewProcessCPUBudget failed: %v", err)
+	}
+	defer b.Close()
+	spinUntilExhausted(t, b, 5*time.Second)
+	if used, err := b.Used(); err != nil || used < 20*time.Millisecond {
+		t.Errorf("Used = (%v, %v), want at least the budget", used, err)
+	}
+}
+
+func TestCPUBudget_PidFD(t *testing.T) {
+	cmd, pfd := startTerminateChild(t, "spin")
+
+	const budget = 30 * time.Millisecond
+	b, err := iofd.NewPidFDCPUBudget(pfd, budget)
+	if err != nil {
+		t.Fatalf("NewPidFDCPUBudget failed: %v", err)
+	}
+	defer b.Close()
+
+	exhausted := false
+	for deadline := time.Now().Add(5 * time.Second); !exhausted && time.Now().Before(deadline); {
+		if !epollWaitReadable(t, b.Fd(), time.Second) {
+			continue
+		}
+		if exhausted, err = b.Check(); err != nil {
+			t.Fatalf("Check failed: %v", err)
+		}
+	}
+	if !exhausted {
+		t.Fatal("budget of the child not exhausted")
+	}
+	if used, err := b.Used(); err != nil || used < budget {
+		t.Errorf("Used = (%v, %v), want at least %v", used, err, budget)
+	}
+
+	// Once the process has exited its PID may be reused
+	if err := pfd.SendSignal(iofd.SIGKILL); err != nil {
+		t.Fatalf("SendSignal failed: %v", err)
+	}
+	_ = cmd.Wait()
+	if _, err := b.Check(); err != iofd.ErrProcessExited {
+		t.Errorf("Check after exit: expected ErrProcessExited, got %v", err)
+	}
+}
+
+func TestCPUBudget_Errors(t *testing.T) {
+	if _, err := iofd.NewProcessCPUBudget(0); err != iofd.ErrInvalidParam {
+		t.Errorf("zero budget: expected ErrInvalidParam, got %v", err)
+	}
+	pfd, err := iofd.NewPidFD(os.Getpid())
+	if err != nil {
+		t.Fatalf("NewPidFD failed: %v", err)
+	}
+	pfd.Close()
+	if _, err := iofd.NewPidFDCPUBudget(pfd, time.Second); err != iofd.ErrClosed {
+		t.Errorf("closed PidFD: expected ErrClosed, got %v", err)
+	}
+
+	b, err := iofd.NewProcessCPUBudget(time.Second)
+	if err != nil {
+		t.Fatalf("NewProcessCPUBudget failed: %v", err)
+	}
+	if err := b.Reset(-time.Second); err != iofd.ErrInvalidParam {
+		t.Errorf("Reset(-1s): expected ErrInvalidParam, got %v", err)
+	}
+	b.Close()
+	if _, err := b.Check(); err != iofd.ErrClosed {
+		t.Errorf("Check after Close: expected ErrClosed, got %v", err)
+	}
+	if _, err := b.Used(); err != iofd.ErrClosed {
+		t.Errorf("Used after Close: expected ErrClosed, got %v", err)
+	}
+}
+
+// =============================================================================
+// Clock Change Tests
+// =============================================================================
+
+// stepRealtime sets the realtime clock to its current value, which the
+// kernel reports as a clock change. Skips the test without CAP_SYS_TIME.
+func stepRealtime(t *testing.T) {
+	t.Helper()
+	var tv syscall.Timeval
+	if err := syscall.Gettimeofday(&tv); err != nil {
+		t.Fatalf("Gettimeofday failed: %v", err)
+	}
+	if err := syscall.Settimeofday(&tv); err != nil {
+		t.Skipf("cannot set the realtime clock: %v", err)
+	}
+}
+
+func TestTimerFD_ArmAtCancelOnSet(t *testing.T) {
+	tfd, err := iofd.NewTimerFDRealtime()
+	if err != nil {
+		t.Fatalf("NewTimerFDRealtime failed: %v", err)
+	}
+	defer tfd.Close()
+	if tfd.Clock() != iofd.CLOCK_REALTIME {
+		t.Errorf("Clock = %d, want CLOCK_REALTIME", tfd.Clock())
+	}
+
+	deadline := time.Now().Add(time.Hour).UnixNano()
+	if err := tfd.ArmAtCancelOnSet(deadline, 0); err != nil {
+		t.Fatalf("ArmAtCancelOnSet failed: %v", err)
+	}
+	if _, err := tfd.Read(); err != iox.ErrWouldBlock {
+		t.Fatalf("Read before clock change: expected ErrWouldBlock, got %v", err)
+	}
+
+	stepRealtime(t)
+	if !epollWaitReadable(t, tfd.Fd(), time.Second) {
+		t.Fatal("timerfd not readable after clock change")
+	}
+	if _, err := tfd.Read(); err != iofd.ErrClockChanged {
+		t.Errorf("Read after clock change: expected ErrClockChanged, got %v", err)
+	}
+	var buf [8]byte
+	if _, err := tfd.ReadInto(buf[:]); err != iox.ErrWouldBlock {
+		t.Errorf("ReadInto after reported change: expected ErrWouldBlock, got %v", err)
+	}
+}
+
+func TestTimerFD_ArmAtCancelOnSetClock(t *testing.T) {
+	tfd, err := iofd.NewTimerFD()
+	if err != nil {
+		t.Fatalf("NewTimerFD failed: %v", err)
+	}
+	defer tfd.Close()
+	if err := tfd.ArmAtCancelOnSet(time.Now().Add(time.Hour).UnixNano(), 0); err != iofd.ErrInvalidParam {
+		t.Errorf("monotonic timer: expected ErrInvalidParam, got %v", err)
+	}
+	tfd.Close()
+	if err := tfd.ArmAt(1, 0); err != iofd.ErrClosed {
+		t.Errorf("ArmAt after Close: expected ErrClosed, got %v", err)
+	}
+}
+
+func TestClockChangeWatcher(t *testing.T) {
+	w, err := iofd.NewClockChangeWatcher()
+	if err != nil {
+		t.Fatalf("NewClockChangeWatcher failed: %v", err)
+	}
+	defer w.Close()
+	if changed, err := w.Changed(); err != nil || changed {
+		t.Fatalf("Changed before change = (%v, %v), want (false, nil)", changed, err)
+	}
+
+	// Changes are reported repeatedly
+	for i := range 2 {
+		stepRealtime(t)
+		if !epollWaitReadable(t, w.Fd(), time.Second) {
+			t.Fatalf("change %d: watcher not readable", i)
+		}
+		if changed, err := w.Changed(); err != nil || !changed {
+			t.Errorf("change %d: Changed = (%v, %v), want (true, nil)", i, changed, err)
+		}
+		if changed, err := w.Changed(); err != nil || changed {
+			t.Errorf("change %d: second Changed = (%v, %v), want (false, nil)", i, changed, err)
+		}
+	}
+
+	w.Close()
+	if _, err := w.Changed(); err != iofd.ErrClosed {
+		t.Errorf("Changed after Close: expected ErrClosed, got %v", err)
+	}
+}
+
+// =============================================================================
+// TimerWheel Tests
+// =============================================================================
+
+// readWheel waits for the wheel's timerfd and runs expired callbacks until
+// done reports true.
+func readWheel(t *testing.T, w *iofd.TimerWheel, timeout time.Duration, done func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for !done() {
+		if time.Now().After(deadline) {
+			t.Fatal("timers did not fire before timeout")
+		}
+		if !epollWaitReadable(t, w.Fd(), time.Until(deadline)) {
+			continue
+		}
+		if _, err := w.Read(); err != nil {
+			t.Fatalf("Read failed: %v", err)
+		}
+	}
+}
+
+func TestTimerWheel_Order(t *testing.T) {
+	w, err := iofd.NewTimerWheel(time.Millisecond)
+	if err != nil {
+		t.Fatalf("NewTimerWheel failed: %v", err)
+	}
+	defer w.Close()
+
+	// Timers run by deadline, then in the order they were added
+	var got []int
+	add := func(id int, d time.Duration) {
+		if _, err := w.Add(d, func() { got = append(got, id) }); err != nil {
+			t.Fatalf("Add failed: %v", err)
+		}
+	}
+	add(3, 30*time.Millisecond)
+	add(1, 10*time.Millisecond)
+	add(0, -time.Second)
+	add(2, 20*time.Millisecond)
+	if w.Len() != 4 {
+		t.Errorf("Len = %d, want 4", w.Len())
+	}
+
+	// Block until all are due so that they expire in a single Read
+	time.Sleep(40 * time.Millisecond)
+	if n, err := w.Read(); err != nil || n != 4 {
+		t.Fatalf("Read = (%d, %v), want (4, nil)", n, err)
+	}
+	if !slices.Equal(got, []int{0, 1, 2, 3}) {
+		t.Errorf("callbacks ran in order %v, want [0 1 2 3]", got)
+	}
+	if w.Len() != 0 {
+		t.Errorf("Len after expiry = %d, want 0", w.Len())
+	}
+	if epollWaitReadable(t, w.Fd(), 20*time.Millisecond) {
+		t.Error("timerfd readable with an empty wheel")
+	}
+}
+
+func TestTimerWheel_Many(t *testing.T) {
+	w, err := iofd.NewTimerWheel(time.Millisecond)
+	if err != nil {
+		t.Fatalf("NewTimerWheel failed: %v", err)
+	}
+	defer w.Close()
+
+	const n = 10000
+	start := time.Now()
+	fired := 0
+	late := time.Duration(0)
+	for i := range n {
+		d := time.Duration(i%50) * time.Millisecond
+		deadline := start.Add(d)
+		if _, err := w.AddAt(deadline, func() {
+			fired++
+			if time.Now().Before(deadline) {
+				t.Errorf("timer %d ran %v early", i, time.Until(deadline))
+			}
+			late = max(late, time.Since(deadline))
+		}); err != nil {
+			t.Fatalf("AddAt failed: %v", err)
+		}
+	}
+	readWheel(t, w, 5*time.Second, func() bool { return fired == n })
+	t.Logf("%d timers fired, max lateness %v", n, late)
+}
+
+func TestTimerWheel_StopReset(t *testing.T) {
+	w, err := iofd.NewTimerWheel(time.Millisecond)
+	if err != nil {
+		t.Fatalf("NewTimerWheel failed: %v", err)
+	}
+	defer w.Close()
+
+	var got []string
+	stopped, err := w.Add(5*time.Millisecond, func() { got = append(got, "stopped") })
+	if err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	reset, err := w.Add(5*time.Millisecond, func() { got = append(got, "reset") })
+	if err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	// A callback can stop a timer due in the same Read
+	victim, err := w.Add(10*time.Millisecond, func() { got = append(got, "victim") })
+	if err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if _, err := w.Add(8*time.Millisecond, func() {
+		got = append(got, "killer")
+		if !victim.Stop() {
+			t.Error("Stop of an expired timer not yet run returned false")
+		}
+	}); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+
+	if !stopped.Stop() {
+		t.Error("Stop of a pending timer returned false")
+	}
+	if stopped.Stop() {
+		t.Error("second Stop returned true")
+	}
+	if pending, err := reset.Reset(30 * time.Millisecond); err != nil || !pending {
+		t.Errorf("Reset = (%v, %v), want (true, nil)", pending, err)
+	}
+	if w.Len() != 3 {
+		t.Errorf("Len = %d, want 3", w.Len())
+	}
+
+	time.Sleep(15 * time.Millisecond)
+	if _, err := w.Read(); err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	if !slices.Equal(got, []string{"killer"}) {
+		t.Fatalf("ran %v, want [killer]", got)
+	}
+	readWheel(t, w, time.Second, func() bool { return len(got) == 2 })
+	if got[1] != "reset" {
+		t.Errorf("ran %v, want [killer reset]", got)
+	}
+	if reset.Stop() {
+		t.Error("Stop after run returned true")
+	}
+
+	// A timer that has run can be reset to run again
+	if pending, err := reset.Reset(0); err != nil || pending {
+		t.Errorf("Reset after run = (%v, %v), want (false, nil)", pending, err)
+	}
+	readWheel(t, w, time.Second, func() bool { return len(got) == 3 })
+}
+
+func TestTimerWheel_Errors(t *testing.T) {
+	if _, err := iofd.NewTimerWheel(0); err != iofd.ErrInvalidParam {
+		t.Errorf("zero tick: expected ErrInvalidParam, got %v", err)
+	}
+	w, err := iofd.NewTimerWheel(time.Millisecond)
+	if err != nil {
+		t.Fatalf("NewTimerWheel failed: %v", err)
+	}
+	if _, err := w.Add(time.Second, nil); err != iofd.ErrInvalidParam {
+		t.Errorf("nil callback: expected ErrInvalidParam, got %v", err)
+	}
+	if _, err := w.AddAt(time.Time{}, func() {}); err != iofd.ErrInvalidParam {
+		t.Errorf("zero deadline: expected ErrInvalidParam, got %v", err)
+	}
+	// Deadlines beyond the clock's range saturate
+	far, err := w.Add(time.Duration(math.MaxInt64), func() {})
+	if err != nil {
+		t.Fatalf("Add(MaxInt64) failed: %v", err)
+	}
+	if far.Deadline() != math.MaxInt64 {
+		t.Errorf("Deadline = %d, want MaxInt64", far.Deadline())
+	}
+
+	w.Close()
+	if _, err := w.Add(time.Second, func() {}); err != iofd.ErrClosed {
+		t.Errorf("Add after Close: expected ErrClosed, got %v", err)
+	}
+	if _, err := far.Reset(time.Second); err != iofd.ErrClosed {
+		t.Errorf("Reset after Close: expected ErrClosed, got %v", err)
+	}
+	if _, err := w.Read(); err != iofd.ErrClosed {
+		t.Errorf("Read after Close: expected ErrClosed, got %v", err)
+	}
+}
+
+// =============================================================================
+// Ticker and AfterFunc Tests
+// =============================================================================
+
+func TestTicker_Missed(t *testing.T) {
+	tk, err := iofd.NewTicker(10 * time.Millisecond)
+	if err != nil {
+		t.Fatalf("NewTicker failed: %v", err)
+	}
+	defer tk.Close()
+	if tk.Period() != 10*time.Millisecond {
+		t.Errorf("Period = %v, want 10ms", tk.Period())
+	}
+	if _, err := tk.Read(); err != iox.ErrWouldBlock {
+		t.Errorf("Read before first tick: expected ErrWouldBlock, got %v", err)
+	}
+
+	if !epollWaitReadable(t, tk.Fd(), time.Second) {
+		t.Fatal("ticker did not tick")
+	}
+	if n, err := tk.Read(); err != nil || n < 1 {
+		t.Fatalf("Read = (%d, %v), want at least one tick", n, err)
+	}
+
+	// Ticks elapsing while unread are reported together as missed
+	missed := tk.Missed()
+	time.Sleep(55 * time.Millisecond)
+	n, err := tk.Read()
+	if err != nil || n < 4 {
+		t.Fatalf("Read after 55ms = (%d, %v), want at least 4 ticks", n, err)
+	}
+	if got := tk.Missed() - missed; got != n-1 {
+		t.Errorf("Missed grew by %d, want %d", got, n-1)
+	}
+	if tk.Ticks() < n+1 {
+		t.Errorf("Ticks = %d, want at least %d", tk.Ticks(), n+1)
+	}
+}
+
+func TestTicker_DriftFree(t *testing.T) {
+	const period = 5 * time.Millisecond
+	start := time.Now()
+	tk, err := iofd.NewTicker(period)
+	if err != nil {
+		t.Fatalf("NewTicker failed: %v", err)
+	}
+	defer tk.Close()
+
+	// Late reads do not shift the schedule: after k ticks, at least k
+	// periods have elapsed, and the tick count catches up
+	for tk.Ticks() < 20 {
+		if !epollWaitReadable(t, tk.Fd(), time.Second) {
+			t.Fatal("ticker did not tick")
+		}
+		if _, err := tk.Read(); err != nil {
+			t.Fatalf("Read failed: %v", err)
+		}
+		// Simulate processing that is not a multiple of the period
+		time.Sleep(3 * time.Millisecond)
+	}
+	elapsed := time.Since(start)
+	if ticks := tk.Ticks(); elapsed < time.Duration(ticks)*period {
+		t.Errorf("%d ticks after only %v", ticks, elapsed)
+	}
+}
+
+func TestTicker_StopReset(t *testing.T) {
+	tk, err := iofd.NewTicker(5 * time.Millisecond)
+	if err != nil {
+		t.Fatalf("NewTicker failed: %v", err)
+	}
+	defer tk.Close()
+
+	time.Sleep(12 * time.Millisecond)
+	if err := tk.Stop(); err != nil {
+		t.Fatalf("Stop failed: %v", err)
+	}
+	if tk.Period() != 0 {
+		t.Errorf("Period after Stop = %v, want 0", tk.Period())
+	}
+	// Unread ticks are discarded
+	if epollWaitReadable(t, tk.Fd(), 20*time.Millisecond) {
+		t.Error("stopped ticker is readable")
+	}
+
+	if err := tk.Reset(time.Millisecond); err != nil {
+		t.Fatalf("Reset failed: %v", err)
+	}
+	if !epollWaitReadable(t, tk.Fd(), time.Second) {
+		t.Fatal("reset ticker did not tick")
+	}
+	if err := tk.Reset(0); err != iofd.ErrInvalidParam {
+		t.Errorf("Reset(0): expected ErrInvalidParam, got %v", err)
+	}
+	if _, err := iofd.NewTicker(-time.Second); err != iofd.ErrInvalidParam {
+		t.Errorf("NewTicker(-1s): expected ErrInvalidParam, got %v", err)
+	}
+
+	tk.Close()
+	if _, err := tk.Read(); err != iofd.ErrClosed {
+		t.Errorf("Read after Close: expected ErrClosed, got %v", err)
+	}
+	if err := tk.Stop(); err != iofd.ErrClosed {
+		t.Errorf("Stop after Close: expected ErrClosed, got %v", err)
+	}
+}
+
+func TestAfterFunc(t *testing.T) {
+	calls := 0
+	ft, err := iofd.AfterFunc(5*time.Millisecond, func() { calls++ })
+	if err != nil {
+		t.Fatalf("AfterFunc failed: %v", err)
+	}
+	defer ft.Close()
+
+	if ran, err := ft.Read(); err != iox.ErrWouldBlock || ran {
+		t.Errorf("Read before expiry = (%v, %v), want (false, ErrWouldBlock)", ran, err)
+	}
+	if !epollWaitReadable(t, ft.Fd(), time.Second) {
+		t.Fatal("timer did not expire")
+	}
+	if ran, err := ft.Read(); err != nil || !ran || calls != 1 {
+		t.Fatalf("Read = (%v, %v) with %d calls, want one call", ran, err, calls)
+	}
+	if stopped, err := ft.Stop(); err != nil || stopped {
+		t.Errorf("Stop after run = (%v, %v), want (false, nil)", stopped, err)
+	}
+
+	// Reset after running schedules another call
+	if active, err := ft.Reset(0); err != nil || active {
+		t.Errorf("Reset after run = (%v, %v), want (false, nil)", active, err)
+	}
+	if !epollWaitReadable(t, ft.Fd(), time.Second) {
+		t.Fatal("reset timer did not expire")
+	}
+
+	// Stop discards an expiration not yet read
+	if stopped, err := ft.Stop(); err != nil || !stopped {
+		t.Errorf("Stop of expired timer = (%v, %v), want (true, nil)", stopped, err)
+	}
+	if epollWaitReadable(t, ft.Fd(), 10*time.Millisecond) {
+		t.Error("stopped timer is readable")
+	}
+	if calls != 1 {
+		t.Errorf("function ran %d times, want 1", calls)
+	}
+
+	if active, err := ft.Reset(time.Hour); err != nil || active {
+		t.Errorf("Reset of stopped timer = (%v, %v), want (false, nil)", active, err)
+	}
+	if active, err := ft.Reset(time.Millisecond); err != nil || !active {
+		t.Errorf("Reset of pending timer = (%v, %v), want (true, nil)", active, err)
+	}
+	if !epollWaitReadable(t, ft.Fd(), time.Second) {
+		t.Fatal("rescheduled timer did not expire")
+	}
+	if ran, err := ft.Read(); err != nil || !ran || calls != 2 {
+		t.Errorf("Read = (%v, %v) with %d calls, want a second call", ran, err, calls)
+	}
+
+	if _, err := iofd.AfterFunc(time.Second, nil); err != iofd.ErrInvalidParam {
+		t.Errorf("nil function: expected ErrInvalidParam, got %v", err)
+	}
+	ft.Close()
+	if _, err := ft.Reset(time.Second); err != iofd.ErrClosed {
+		t.Errorf("Reset after Close: expected ErrClosed, got %v", err)
+	}
+}
+
+// =============================================================================
+// Clock Tests
+// =============================================================================
+
+func TestNow(t *testing.T) {
+	wall := time.Now().UnixNano()
+	now, err := iofd.Now(iofd.CLOCK_REALTIME)
+	if err != nil || now < wall || now-wall > int64(time.Second) {
+		t.Errorf("Now(REALTIME) = (%d, %v), want about %d", now, err, wall)
+	}
+
+	mono, err := iofd.Now(iofd.CLOCK_MONOTONIC)
+	if err != nil {
+		t.Fatalf("Now(MONOTONIC) failed: %v", err)
+	}
+	boot, err := iofd.Now(iofd.CLOCK_BOOTTIME)
+	if err != nil {
+		t.Fatalf("Now(BOOTTIME) failed: %v", err)
+	}
+	// Boot time includes suspend, which monotonic time does not
+	if boot < mono {
+		t.Errorf("BOOTTIME %d behind MONOTONIC %d", boot, mono)
+	}
+	if cpu, err := iofd.Now(iofd.CLOCK_PROCESS_CPUTIME_ID); err != nil || cpu <= 0 {
+		t.Errorf("Now(PROCESS_CPUTIME_ID) = (%d, %v)", cpu, err)
+	}
+	if _, err := iofd.Now(-100); err != iofd.ErrInvalidParam {
+		t.Errorf("invalid clock: expected ErrInvalidParam, got %v", err)
+	}
+
+	// Now yields deadlines in the timer's clock for ArmAt
+	tfd, err := iofd.NewTimerFD()
+	if err != nil {
+		t.Fatalf("NewTimerFD failed: %v", err)
+	}
+	defer tfd.Close()
+	if err := tfd.ArmAt(mono+int64(time.Hour), 0); err != nil {
+		t.Fatalf("ArmAt failed: %v", err)
+	}
+	if remaining, _, err := tfd.GetTime(); err != nil || remaining <= int64(59*time.Minute) || remaining > int64(time.Hour) {
+		t.Errorf("remaining = (%v, %v), want about 1h", time.Duration(remaining), err)
+	}
+}
+
+func TestClockResolution(t *testing.T) {
+	for _, clockid := range []int{iofd.CLOCK_REALTIME, iofd.CLOCK_MONOTONIC, iofd.CLOCK_BOOTTIME, iofd.CLOCK_THREAD_CPUTIME_ID} {
+		res, err := iofd.ClockResolution(clockid)
+		if err != nil || res <= 0 || res > time.Second {
+			t.Errorf("ClockResolution(%d) = (%v, %v)", clockid, res, err)
+		}
+	}
+	if _, err := iofd.ClockResolution(-100); err != iofd.ErrInvalidParam {
+		t.Errorf("invalid clock: expected ErrInvalidParam, got %v", err)
+	}
+}
+
+func TestTimensOffsets(t *testing.T) {
+	self, err := iofd.ReadTimensOffsets()
+	if err != nil {
+		t.Fatalf("ReadTimensOffsets failed: %v", err)
+	}
+	pfd, err := iofd.NewPidFD(os.Getpid())
+	if err != nil {
+		t.Fatalf("NewPidFD failed: %v", err)
+	}
+	defer pfd.Close()
+	byPid, err := pfd.TimensOffsets()
+	if err != nil {
+		t.Fatalf("PidFD.TimensOffsets failed: %v", err)
+	}
+	if byPid != self {
+		t.Errorf("offsets through pidfd %+v differ from %+v", byPid, self)
+	}
+	mono, _ := iofd.Now(iofd.CLOCK_MONOTONIC)
+	if self.FromHost(iofd.CLOCK_MONOTONIC, self.ToHost(iofd.CLOCK_MONOTONIC, mono)) != mono {
+		t.Error("ToHost and FromHost are not inverse")
+	}
+
+	pfd.Close()
+	if _, err := pfd.TimensOffsets(); err != iofd.ErrClosed {
+		t.Errorf("TimensOffsets after Close: expected ErrClosed, got %v", err)
+	}
+}
+
+// =============================================================================
+// Blocking Mode and Context Wait Tests
+// =============================================================================
+
+// isNonblock reports whether the file status flags of fd include O_NONBLOCK.
+func isNonblock(t *testing.T, fd int) bool {
+	t.Helper()
+	flags, _, errno := syscall.Syscall(syscall.SYS_FCNTL, uintptr(fd), syscall.F_GETFL, 0)
+	if errno != 0 {
+		t.Fatalf("F_GETFL failed: %v", errno)
+	}
+	return flags&syscall.O_NONBLOCK != 0
+}
+
+func TestEventFD_Blocking(t *testing.T) {
+	efd, err := iofd.NewEventFDBlocking(2)
+	if err != nil {
+		t.Fatalf("NewEventFDBlocking failed: %v", err)
+	}
+	defer efd.Close()
+	if isNonblock(t, efd.Fd()) {
+		t.Error("blocking eventfd has O_NONBLOCK")
+	}
+	// A non-zero counter is read without blocking
+	if val, err := efd.Wait(); err != nil || val != 2 {
+		t.Errorf("Wait = (%d, %v), want (2, nil)", val, err)
+	}
+
+	// WaitContext parks until another goroutine signals
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		_ = efd.Signal(5)
+	}()
+	if val, err := efd.WaitContext(context.Background()); err != nil || val != 5 {
+		t.Errorf("WaitContext = (%d, %v), want (5, nil)", val, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := efd.WaitContext(ctx); err != context.DeadlineExceeded {
+		t.Errorf("WaitContext on zero counter: expected DeadlineExceeded, got %v", err)
+	}
+}
+
+func TestEventFD_BlockingWaitGC(t *testing.T) {
+	efd, err := iofd.NewEventFDBlocking(0)
+	if err != nil {
+		t.Fatalf("NewEventFDBlocking failed: %v", err)
+	}
+	defer efd.Close()
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := efd.Wait()
+		done <- err
+	}()
+	// A Wait parked on a zero counter must not stall the stop-the-world phase
+	time.Sleep(20 * time.Millisecond)
+	runtime.GC()
+	if err := efd.Signal(1); err != nil {
+		t.Fatalf("Signal failed: %v", err)
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Wait failed: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Wait did not return")
+	}
+}
+
+func TestTimerFD_BlockingRead(t *testing.T) {
+	tfd, err := iofd.NewTimerFDClock(iofd.CLOCK_MONOTONIC, iofd.TFD_CLOEXEC)
+	if err != nil {
+		t.Fatalf("NewTimerFDClock failed: %v", err)
+	}
+	defer tfd.Close()
+	if err := tfd.ArmDuration(30*time.Millisecond, 0); err != nil {
+		t.Fatalf("ArmDuration failed: %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := tfd.Read()
+		done <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+	runtime.GC()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Read failed: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Read did not return")
+	}
+}
+
+func TestEventFD_SemaphoreBlocking(t *testing.T) {
+	efd, err := iofd.NewEventFDSemaphoreBlocking(2)
+	if err != nil {
+		t.Fatalf("NewEventFDSemaphoreBlocking failed: %v", err)
+	}
+	defer efd.Close()
+	if isNonblock(t, efd.Fd()) {
+		t.Error("blocking eventfd has O_NONBLOCK")
+	}
+	for i := range 2 {
+		if val, err := efd.WaitContext(context.Background()); err != nil || val != 1 {
+			t.Errorf("WaitContext %d = (%d, %v), want (1, nil)", i, val, err)
+		}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+	if _, err := efd.WaitContext(ctx); err != context.Canceled {
+		t.Errorf("WaitContext on exhausted semaphore: expected Canceled, got %v", err)
+	}
+}
+
+func TestEventFD_WaitContext(t *testing.T) {
+	efd, err := iofd.NewEventFD(0)
+	if err != nil {
+		t.Fatalf("NewEventFD failed: %v", err)
+	}
+	defer efd.Close()
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		_ = efd.Signal(3)
+	}()
+	if val, err := efd.WaitContext(context.Background()); err != nil || val != 3 {
+		t.Errorf("WaitContext = (%d, %v), want (3, nil)", val, err)
+	}
+
+	// A done context wins over a pending value
+	_ = efd.Signal(1)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := efd.WaitContext(ctx); err != context.Canceled {
+		t.Errorf("WaitContext with canceled context: expected Canceled, got %v", err)
+	}
+	if val, err := efd.Wait(); err != nil || val != 1 {
+		t.Errorf("value consumed by canceled WaitContext: Wait = (%d, %v)", val, err)
+	}
+
+	efd.Close()
+	if _, err := efd.WaitContext(context.Background()); err != iofd.ErrClosed {
+		t.Errorf("WaitContext after Close: expected ErrClosed, got %v", err)
+	}
+}
+
+func TestTimerFD_ReadContext(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		flags int
+	}{
+		{"nonblocking", iofd.TFD_NONBLOCK | iofd.TFD_CLOEXEC},
+		{"blocking", iofd.TFD_CLOEXEC},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			tfd, err := iofd.NewTimerFDClock(iofd.CLOCK_MONOTONIC, tc.flags)
+			if err != nil {
+				t.Fatalf("NewTimerFDClock failed: %v", err)
+			}
+			defer tfd.Close()
+
+			if err := tfd.ArmDuration(10*time.Millisecond, 0); err != nil {
+				t.Fatalf("ArmDuration failed: %v", err)
+			}
+			if n, err := tfd.ReadContext(context.Background()); err != nil || n != 1 {
+				t.Errorf("ReadContext = (%d, %v), want (1, nil)", n, err)
+			}
+
+			if err := tfd.ArmDuration(time.Hour, 0); err != nil {
+				t.Fatalf("ArmDuration failed: %v", err)
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+			defer cancel()
+			if _, err := tfd.ReadContext(ctx); err != context.DeadlineExceeded {
+				t.Errorf("ReadContext before expiry: expected DeadlineExceeded, got %v", err)
+			}
+		})
+	}
+}
+
+func TestSignalFD_ReadContext(t *testing.T) {
+	sfd := newThreadSignalFD(t, iofd.SIGUSR1)
+
+	pid, tid := os.Getpid(), sfd.TID()
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		_ = syscall.Tgkill(pid, tid, syscall.SIGUSR1)
+	}()
+	info, err := sfd.ReadContext(context.Background())
+	if err != nil {
+		t.Fatalf("ReadContext failed: %v", err)
+	}
+	if info.Signo != iofd.SIGUSR1 {
+		t.Errorf("ReadContext: signo %d, want SIGUSR1", info.Signo)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := sfd.ReadContext(ctx); err != context.DeadlineExceeded {
+		t.Errorf("ReadContext without signal: expected DeadlineExceeded, got %v", err)
+	}
+}
+
+func TestPidFD_GetHandleBlocking(t *testing.T) {
+	pfd, err := iofd.NewPidFD(os.Getpid())
+	if err != nil {
+		t.Fatalf("NewPidFD failed: %v", err)
+	}
+	defer pfd.Close()
+	efd, err := iofd.NewEventFDBlocking(0)
+	if err != nil {
+		t.Fatalf("NewEventFDBlocking failed: %v", err)
+	}
+	defer efd.Close()
+
+	h, err := pfd.GetHandle(efd.Fd())
+	if errors.Is(err, iofd.ErrPermission) {
+		t.Skipf("pidfd_getfd not permitted: %v", err)
+	}
+	if err != nil {
+		t.Fatalf("GetHandle failed: %v", err)
+	}
+	defer h.Close()
+	dup, ok := h.(*iofd.EventFD)
+	if !ok {
+		t.Fatalf("GetHandle returned %T, want *EventFD", h)
+	}
+	// The adopted descriptor is known to block, so WaitContext polls first
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := dup.WaitContext(ctx); err != context.DeadlineExceeded {
+		t.Errorf("WaitContext: expected DeadlineExceeded, got %v", err)
+	}
+}
+
+// =============================================================================
+// Semaphore Tests
+// =============================================================================
+
+func TestSemaphore(t *testing.T) {
+	s, err := iofd.NewSemaphore(2)
+	if err != nil {
+		t.Fatalf("NewSemaphore failed: %v", err)
+	}
+	defer s.Close()
+
+	if ok, err := s.TryAcquire(3); ok || err != nil {
+		t.Errorf("TryAcquire(3) of 2 = (%v, %v), want (false, nil)", ok, err)
+	}
+	if ok, err := s.TryAcquire(2); !ok || err != nil {
+		t.Errorf("TryAcquire(2) of 2 = (%v, %v), want (true, nil)", ok, err)
+	}
+	if epollWaitReadable(t, s.Fd(), 0) {
+		t.Error("Semaphore readable with no units")
+	}
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		_ = s.Release(1)
+	}()
+	if err := s.Acquire(context.Background(), 1); err != nil {
+		t.Errorf("Acquire after Release failed: %v", err)
+	}
+
+	// A canceled Acquire gives back the units it took
+	if err := s.Release(2); err != nil {
+		t.Fatalf("Release failed: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := s.Acquire(ctx, 3); err != context.DeadlineExceeded {
+		t.Errorf("Acquire(3) of 2: expected DeadlineExceeded, got %v", err)
+	}
+	if ok, err := s.TryAcquire(2); !ok || err != nil {
+		t.Errorf("TryAcquire(2) after canceled Acquire = (%v, %v), want (true, nil)", ok, err)
+	}
+
+	if err := s.Release(math.MaxUint64 - 1); err != nil {
+		t.Fatalf("Release to the maximum failed: %v", err)
+	}
+	if err := s.Release(1); err != iofd.ErrOverflow {
+		t.Errorf("Release beyond the maximum: expected ErrOverflow, got %v", err)
+	}
+
+	s.Close()
+	if err := s.Acquire(context.Background(), 1); err != iofd.ErrClosed {
+		t.Errorf("Acquire after Close: expected ErrClosed, got %v", err)
+	}
+	if _, err := s.TryAcquire(1); err != iofd.ErrClosed {
+		t.Errorf("TryAcquire after Close: expected ErrClosed, got %v", err)
+	}
+}
+
+func TestSemaphore_Adopt(t *testing.T) {
+	efd, err := iofd.NewEventFD(1)
+	if err != nil {
+		t.Fatalf("NewEventFD failed: %v", err)
+	}
+	defer efd.Close()
+	if _, err := iofd.NewSemaphoreEventFD(efd); err != iofd.ErrInvalidParam {
+		t.Errorf("NewSemaphoreEventFD of a counter eventfd: expected ErrInvalidParam, got %v", err)
+	}
+	tfd, err := iofd.NewTimerFD()
+	if err != nil {
+		t.Fatalf("NewTimerFD failed: %v", err)
+	}
+	defer tfd.Close()
+	if _, err := iofd.NewSemaphoreFd(tfd.Fd()); err != iofd.ErrInvalidParam {
+		t.Errorf("NewSemaphoreFd of a timerfd: expected ErrInvalidParam, got %v", err)
+	}
+
+	// A blocking semaphore eventfd adopted through a pidfd
+	src, err := iofd.NewEventFDSemaphoreBlocking(1)
+	if err != nil {
+		t.Fatalf("NewEventFDSemaphoreBlocking failed: %v", err)
+	}
+	defer src.Close()
+	pfd, err := iofd.NewPidFD(os.Getpid())
+	if err != nil {
+		t.Fatalf("NewPidFD failed: %v", err)
+	}
+	defer pfd.Close()
+	h, err := pfd.GetHandle(src.Fd())
+	if errors.Is(err, iofd.ErrPermission) {
+		t.Skipf("pidfd_getfd not permitted: %v", err)
+	}
+	if err != nil {
+		t.Fatalf("GetHandle failed: %v", err)
+	}
+	s, err := iofd.NewSemaphoreEventFD(h.(*iofd.EventFD))
+	if err != nil {
+		h.Close()
+		t.Fatalf("NewSemaphoreEventFD failed: %v", err)
+	}
+	defer s.Close()
+	// The shared file description is left blocking
+	if isNonblock(t, src.Fd()) {
+		t.Error("adopting the eventfd set O_NONBLOCK on its description")
+	}
+	if ok, err := s.TryAcquire(1); !ok || err != nil {
+		t.Errorf("TryAcquire = (%v, %v), want (true, nil)", ok, err)
+	}
+	// The emptied eventfd must not block TryAcquire
+	if ok, err := s.TryAcquire(1); ok || err != nil {
+		t.Errorf("TryAcquire on empty = (%v, %v), want (false, nil)", ok, err)
+	}
+}
+
+func TestSemaphore_AcquireReleasesP(t *testing.T) {
+	s, err := iofd.NewSemaphore(0)
+	if err != nil {
+		t.Fatalf("NewSemaphore failed: %v", err)
+	}
+	defer s.Close()
+	checkReleasesP(t, func() error {
+		return s.Acquire(context.Background(), 1)
+	}, func() { _ = s.Release(1) })
+}
+
+// inheritFd returns a duplicate of fd as an *os.File for exec.Cmd.ExtraFiles.
+func inheritFd(t *testing.T, fd int) *os.File {
+	t.Helper()
+	dup, err := syscall.Dup(fd)
+	if err != nil {
+		t.Fatalf("dup failed: %v", err)
+	}
+	f := os.NewFile(uintptr(dup), "inherited")
+	t.Cleanup(func() { f.Close() })
+	return f
+}
+
+func TestSemaphore_Process(t *testing.T) {
+	s, err := iofd.NewSemaphore(0)
+	if err != nil {
+		t.Fatalf("NewSemaphore failed: %v", err)
+	}
+	defer s.Close()
+
+	cmd := helperCommand(t, "semaphore")
+	cmd.ExtraFiles = []*os.File{inheritFd(t, s.Fd())}
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	// The child waits for the two units released here
+	if err := s.Release(2); err != nil {
+		t.Fatalf("Release failed: %v", err)
+	}
+	if err := cmd.Wait(); err != nil {
+		t.Fatalf("helper failed: %v", err)
+	}
+	// The child released five units of its own
+	if ok, err := s.TryAcquire(5); !ok || err != nil {
+		t.Errorf("TryAcquire(5) = (%v, %v), want (true, nil)", ok, err)
+	}
+	if ok, err := s.TryAcquire(1); ok || err != nil {
+		t.Errorf("TryAcquire on empty = (%v, %v), want (false, nil)", ok, err)
+	}
+}
+
+func TestMutex(t *testing.T) {
+	m, err := iofd.NewMutex()
+	if err != nil {
+		t.Fatalf("NewMutex failed: %v", err)
+	}
+	defer m.Close()
+
+	if err := m.Unlock(); err != iofd.ErrInvalidParam {
+		t.Errorf("Unlock of unlocked Mutex: expected ErrInvalidParam, got %v", err)
+	}
+	if ok, err := m.TryLock(); !ok || err != nil {
+		t.Fatalf("TryLock = (%v, %v), want (true, nil)", ok, err)
+	}
+	if ok, err := m.TryLock(); ok || err != nil {
+		t.Errorf("TryLock of locked Mutex = (%v, %v), want (false, nil)", ok, err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := m.Lock(ctx); err != context.DeadlineExceeded {
+		t.Errorf("Lock of locked Mutex: expected DeadlineExceeded, got %v", err)
+	}
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		_ = m.Unlock()
+	}()
+	if err := m.Lock(context.Background()); err != nil {
+		t.Errorf("Lock after Unlock failed: %v", err)
+	}
+	if err := m.Unlock(); err != nil {
+		t.Errorf("Unlock failed: %v", err)
+	}
+}
+
+func TestMutex_Process(t *testing.T) {
+	m, err := iofd.NewMutex()
+	if err != nil {
+		t.Fatalf("NewMutex failed: %v", err)
+	}
+	defer m.Close()
+	if err := m.Lock(context.Background()); err != nil {
+		t.Fatalf("Lock failed: %v", err)
+	}
+
+	cmd := helperCommand(t, "mutex")
+	cmd.ExtraFiles = []*os.File{inheritFd(t, m.Fd())}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatalf("StdoutPipe failed: %v", err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	// The child has seen the mutex held before it is unlocked
+	line, err := bufio.NewReader(stdout).ReadString('\n')
+	if err != nil || line != "ready\n" {
+		t.Fatalf("helper output = %q, %v", line, err)
+	}
+	if err := m.Unlock(); err != nil {
+		t.Fatalf("Unlock failed: %v", err)
+	}
+	if err := cmd.Wait(); err != nil {
+		t.Fatalf("helper failed: %v", err)
+	}
+	if ok, err := m.TryLock(); !ok || err != nil {
+		t.Errorf("TryLock after the child unlocked = (%v, %v), want (true, nil)", ok, err)
+	}
+}
+
+// =============================================================================
+// Notification Primitive Tests
+// =============================================================================
+
+func TestNotifier(t *testing.T) {
+	n, err := iofd.NewNotifier()
+	if err != nil {
+		t.Fatalf("NewNotifier failed: %v", err)
+	}
+	defer n.Close()
+
+	if err := n.Read(); err != iox.ErrWouldBlock {
+		t.Errorf("Read without Notify: expected ErrWouldBlock, got %v", err)
+	}
+	for range 3 {
+		if err := n.Notify(); err != nil {
+			t.Fatalf("Notify failed: %v", err)
+		}
+	}
+	if !n.Pending() || !epollWaitReadable(t, n.Fd(), 0) {
+		t.Error("Notifier not pending and readable after Notify")
+	}
+	// The eventfd counter shows that the burst was written once
+	info, err := os.ReadFile(fmt.Sprintf("/proc/self/fdinfo/%d", n.Fd()))
+	if err != nil {
+		t.Fatalf("reading fdinfo failed: %v", err)
+	}
+	for line := range strings.Lines(string(info)) {
+		if v, ok := strings.CutPrefix(line, "eventfd-count:"); ok && strings.TrimSpace(v) != "1" {
+			t.Errorf("eventfd-count = %q, want 1", strings.TrimSpace(v))
+		}
+	}
+	if err := n.Read(); err != nil {
+		t.Errorf("Read failed: %v", err)
+	}
+	if n.Pending() || epollWaitReadable(t, n.Fd(), 0) {
+		t.Error("Notifier still pending after Read")
+	}
+	if err := n.Notify(); err != nil {
+		t.Fatalf("Notify after Read failed: %v", err)
+	}
+	if !epollWaitReadable(t, n.Fd(), 0) {
+		t.Error("Notifier not readable after second Notify")
+	}
+
+	n.Close()
+	if err := n.Read(); err != iofd.ErrClosed {
+		t.Errorf("Read after Close: expected ErrClosed, got %v", err)
+	}
+}
+
+func TestLatch(t *testing.T) {
+	l, err := iofd.NewLatch()
+	if err != nil {
+		t.Fatalf("NewLatch failed: %v", err)
+	}
+	defer l.Close()
+
+	if l.Fired() {
+		t.Error("new Latch has fired")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := l.Wait(ctx); err != context.DeadlineExceeded {
+		t.Errorf("Wait before Fire: expected DeadlineExceeded, got %v", err)
+	}
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		_ = l.Fire()
+	}()
+	if err := l.Wait(context.Background()); err != nil {
+		t.Errorf("Wait failed: %v", err)
+	}
+	// The latch stays fired for later waiters
+	for range 2 {
+		if err := l.Wait(context.Background()); err != nil {
+			t.Errorf("Wait after Fire failed: %v", err)
+		}
+	}
+	if err := l.Fire(); err != nil {
+		t.Errorf("second Fire failed: %v", err)
+	}
+	if !l.Fired() || !epollWaitReadable(t, l.Fd(), 0) {
+		t.Error("Latch not fired and readable")
+	}
+
+	l.Close()
+	if err := l.Wait(context.Background()); err != iofd.ErrClosed {
+		t.Errorf("Wait after Close: expected ErrClosed, got %v", err)
+	}
+}
+
+func TestLatch_Shared(t *testing.T) {
+	l, err := iofd.NewLatch()
+	if err != nil {
+		t.Fatalf("NewLatch failed: %v", err)
+	}
+	defer l.Close()
+	pfd, err := iofd.NewPidFD(os.Getpid())
+	if err != nil {
+		t.Fatalf("NewPidFD failed: %v", err)
+	}
+	defer pfd.Close()
+	h, err := pfd.GetHandle(l.Fd())
+	if errors.Is(err, iofd.ErrPermission) {
+		t.Skipf("pidfd_getfd not permitted: %v", err)
+	}
+	if err != nil {
+		t.Fatalf("GetHandle failed: %v", err)
+	}
+	defer h.Close()
+	// Firing through the shared eventfd is seen by the Latch
+	if err := h.(*iofd.EventFD).Signal(1); err != nil {
+		t.Fatalf("Signal failed: %v", err)
+	}
+	if !l.Fired() {
+		t.Error("Latch not fired through the shared descriptor")
+	}
+}
+
+func TestLatch_WaitReleasesP(t *testing.T) {
+	l, err := iofd.NewLatch()
+	if err != nil {
+		t.Fatalf("NewLatch failed: %v", err)
+	}
+	defer l.Close()
+	checkReleasesP(t, func() error {
+		return l.Wait(context.Background())
+	}, func() { _ = l.Fire() })
+
+	wg, err := iofd.NewWaitGroup()
+	if err != nil {
+		t.Fatalf("NewWaitGroup failed: %v", err)
+	}
+	defer wg.Close()
+	if err := wg.Add(1); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	checkReleasesP(t, func() error {
+		return wg.Wait(context.Background())
+	}, func() { _ = wg.Done() })
+}
+
+func TestWaitGroup(t *testing.T) {
+	wg, err := iofd.NewWaitGroup()
+	if err != nil {
+		t.Fatalf("NewWaitGroup failed: %v", err)
+	}
+	defer wg.Close()
+
+	if !epollWaitReadable(t, wg.Fd(), 0) {
+		t.Error("WaitGroup with zero counter not readable")
+	}
+	if err := wg.Wait(context.Background()); err != nil {
+		t.Errorf("Wait with zero counter failed: %v", err)
+	}
+	if err := wg.Done(); err != iofd.ErrInvalidParam {
+		t.Errorf("Done with zero counter: expected ErrInvalidParam, got %v", err)
+	}
+
+	if err := wg.Add(3); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if epollWaitReadable(t, wg.Fd(), 0) {
+		t.Error("WaitGroup readable with pending tasks")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := wg.Wait(ctx); err != context.DeadlineExceeded {
+		t.Errorf("Wait with pending tasks: expected DeadlineExceeded, got %v", err)
+	}
+
+	for range 3 {
+		go func() {
+			time.Sleep(10 * time.Millisecond)
+			_ = wg.Done()
+		}()
+	}
+	if err := wg.Wait(context.Background()); err != nil {
+		t.Errorf("Wait failed: %v", err)
+	}
+	if wg.Count() != 0 || !epollWaitReadable(t, wg.Fd(), 0) {
+		t.Errorf("WaitGroup after Done: count %d, want 0 and readable", wg.Count())
+	}
+
+	// The group can be reused
+	if err := wg.Add(1); err != nil {
+		t.Fatalf("Add after reuse failed: %v", err)
+	}
+	if epollWaitReadable(t, wg.Fd(), 0) {
+		t.Error("reused WaitGroup readable with a pending task")
+	}
+	if err := wg.Add(-1); err != nil || !epollWaitReadable(t, wg.Fd(), 0) {
+		t.Errorf("Add(-1) = %v, want nil and readable", err)
+	}
+}
+
+// =============================================================================
+// Poll Tests
+// =============================================================================
+
+func TestPoll(t *testing.T) {
+	efd, err := iofd.NewEventFD(0)
+	if err != nil {
+		t.Fatalf("NewEventFD failed: %v", err)
+	}
+	defer efd.Close()
+	tfd, err := iofd.NewTimerFD()
+	if err != nil {
+		t.Fatalf("NewTimerFD failed: %v", err)
+	}
+	defer tfd.Close()
+	closed, err := iofd.NewEventFD(0)
+	if err != nil {
+		t.Fatalf("NewEventFD failed: %v", err)
+	}
+	closed.Close()
+
+	fds := []iofd.PollEntry{
+		iofd.PollEntryOf(efd, iofd.POLLIN),
+		iofd.PollEntryOf(tfd, iofd.POLLIN),
+		iofd.PollEntryOf(closed, iofd.POLLIN), // Fd -1, skipped
+	}
+	if n, err := iofd.Poll(fds, 0); n != 0 || err != nil {
+		t.Errorf("Poll with nothing ready = (%d, %v), want (0, nil)", n, err)
+	}
+	start := time.Now()
+	if n, err := iofd.Poll(fds, 20*time.Millisecond); n != 0 || err != nil {
+		t.Errorf("Poll until timeout = (%d, %v), want (0, nil)", n, err)
+	}
+	if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+		t.Errorf("Poll returned after %v, before its timeout", elapsed)
+	}
+
+	if err := tfd.ArmDuration(10*time.Millisecond, 0); err != nil {
+		t.Fatalf("ArmDuration failed: %v", err)
+	}
+	n, err := iofd.Poll(fds, -1)
+	if n != 1 || err != nil {
+		t.Fatalf("Poll until timer expiry = (%d, %v), want (1, nil)", n, err)
+	}
+	if fds[0].Revents != 0 || fds[1].Revents != iofd.POLLIN || fds[2].Revents != 0 {
+		t.Errorf("Revents = %#x %#x %#x, want 0 POLLIN 0", fds[0].Revents, fds[1].Revents, fds[2].Revents)
+	}
+
+	// An eventfd is always writable; requested and unrequested events
+	fds[0].Events = iofd.POLLIN | iofd.POLLOUT
+	_ = efd.Signal(1)
+	if n, err := iofd.Poll(fds, 0); n != 2 || err != nil {
+		t.Errorf("Poll = (%d, %v), want (2, nil)", n, err)
+	}
+	if fds[0].Revents != iofd.POLLIN|iofd.POLLOUT {
+		t.Errorf("eventfd Revents = %#x, want POLLIN|POLLOUT", fds[0].Revents)
+	}
+
+	// A descriptor number that is not open reports POLLNVAL
+	nval := []iofd.PollEntry{{Fd: int32(efd.Fd()), Events: iofd.POLLIN}}
+	efd.Close()
+	if n, err := iofd.Poll(nval, 0); n != 1 || err != nil || nval[0].Revents != iofd.POLLNVAL {
+		t.Errorf("Poll on closed fd = (%d, %v), Revents %#x, want POLLNVAL", n, err, nval[0].Revents)
+	}
+}
+
+func TestPoll_NoAlloc(t *testing.T) {
+	efd, err := iofd.NewEventFD(1)
+	if err != nil {
+		t.Fatalf("NewEventFD failed: %v", err)
+	}
+	defer efd.Close()
+	fds := []iofd.PollEntry{iofd.PollEntryOf(efd, iofd.POLLIN)}
+	allocs := testing.AllocsPerRun(100, func() {
+		_, _ = iofd.Poll(fds, time.Second)
+		_, _ = iofd.Poll(fds, -1, iofd.PollInterruptible)
+	})
+	if allocs != 0 {
+		t.Errorf("Poll allocated %v times per run, want 0", allocs)
+	}
+}
+
+// checkReleasesP runs wait on another goroutine with GOMAXPROCS(1) and
+// checks that, while it is blocked, short sleeps of the test goroutine
+// are not delayed until the runtime preempts it. release must make wait
+// return.
+func checkReleasesP(t *testing.T, wait func() error, release func()) {
+	t.Helper()
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(1))
+	errc := make(chan error, 1)
+	go func() { errc <- wait() }()
+	time.Sleep(5 * time.Millisecond)
+
+	var lat [9]time.Duration
+	for i := range lat {
+		start := time.Now()
+		time.Sleep(100 * time.Microsecond)
+		lat[i] = time.Since(start)
+	}
+	slices.Sort(lat[:])
+	release()
+	select {
+	case err := <-errc:
+		if err != nil {
+			t.Errorf("wait failed: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("wait did not return")
+	}
+	if median := lat[len(lat)/2]; median > 5*time.Millisecond {
+		t.Errorf("100µs sleeps took %v while the wait was blocked, want the P released", median)
+	}
+}
+
+func TestPoll_ReleasesP(t *testing.T) {
+	efd, err := iofd.NewEventFD(0)
+	if err != nil {
+		t.Fatalf("NewEventFD failed: %v", err)
+	}
+	defer efd.Close()
+	checkReleasesP(t, func() error {
+		fds := []iofd.PollEntry{iofd.PollEntryOf(efd, iofd.POLLIN)}
+		_, err := iofd.Poll(fds, -1)
+		return err
+	}, func() { _ = efd.Signal(1) })
+}
+
+func TestWaitContext_ReleasesP(t *testing.T) {
+	t.Run("EventFD.WaitContext", func(t *testing.T) {
+		efd, err := iofd.NewEventFD(0)
+		if err != nil {
+			t.Fatalf("NewEventFD failed: %v", err)
+		}
+		defer efd.Close()
+		checkReleasesP(t, func() error {
+			_, err := efd.WaitContext(context.Background())
+			return err
+		}, func() { _ = efd.Signal(1) })
+	})
+	t.Run("EventFD.Wait", func(t *testing.T) {
+		efd, err := iofd.NewEventFDBlocking(0)
+		if err != nil {
+			t.Fatalf("NewEventFDBlocking failed: %v", err)
+		}
+		defer efd.Close()
+		checkReleasesP(t, func() error {
+			_, err := efd.Wait()
+			return err
+		}, func() { _ = efd.Signal(1) })
+	})
+	t.Run("TimerFD.ReadContext", func(t *testing.T) {
+		tfd, err := iofd.NewTimerFD()
+		if err != nil {
+			t.Fatalf("NewTimerFD failed: %v", err)
+		}
+		defer tfd.Close()
+		ctx, cancel := context.WithCancel(context.Background())
+		checkReleasesP(t, func() error {
+			if _, err := tfd.ReadContext(ctx); err != context.Canceled {
+				return err
+			}
+			return nil
+		}, cancel)
+	})
+}
+
+func TestPPoll_Mask(t *testing.T) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, syscall.SIGUSR2)
+	defer signal.Stop(ch)
+
+	prev, err := iofd.BlockSignals(iofd.SigSetOf(iofd.SIGUSR2))
+	if err != nil {
+		t.Fatalf("BlockSignals failed: %v", err)
+	}
+	defer iofd.SetSignalMask(prev)
+	raise(t, iofd.SIGUSR2)
+
+	// The pending signal stays blocked during a plain Poll
+	if n, err := iofd.Poll(nil, 10*time.Millisecond, iofd.PollInterruptible); n != 0 && err != iofd.ErrInterrupted {
+		t.Errorf("Poll with signal blocked = (%d, %v)", n, err)
+	}
+	select {
+	case <-ch:
+		t.Fatal("blocked signal delivered during Poll")
+	default:
+	}
+
+	// PPoll unblocks it for the wait, which it interrupts
+	mask := prev
+	mask.Del(iofd.SIGUSR2)
+	if _, err := iofd.PPoll(nil, 5*time.Second, &mask, iofd.PollInterruptible); err != iofd.ErrInterrupted {
+		t.Errorf("PPoll with signal unblocked: expected ErrInterrupted, got %v", err)
+	}
+	select {
+	case <-ch:
+	case <-time.After(5 * time.Second):
+		t.Error("signal unblocked by PPoll not delivered")
+	}
+	if cur, err := iofd.CurrentMask(); err != nil || !cur.Has(iofd.SIGUSR2) {
+		t.Errorf("mask after PPoll = %v, %v; want SIGUSR2 blocked again", cur, err)
+	}
+}
+
+// =============================================================================
+// Epoll Tests
+// =============================================================================
+
+func TestEpoll(t *testing.T) {
+	ep, err := iofd.NewEpoll()
+	if err != nil {
+		t.Fatalf("NewEpoll failed: %v", err)
+	}
+	defer ep.Close()
+	efd, err := iofd.NewEventFD(0)
+	if err != nil {
+		t.Fatalf("NewEventFD failed: %v", err)
+	}
+	defer efd.Close()
+	tfd, err := iofd.NewTimerFD()
+	if err != nil {
+		t.Fatalf("NewTimerFD failed: %v", err)
+	}
+	defer tfd.Close()
+
+	if err := ep.Add(efd, iofd.EPOLLIN, 42); err != nil {
+		t.Fatalf("Add eventfd failed: %v", err)
+	}
+	if err := ep.Add(tfd, iofd.EPOLLIN, math.MaxUint64); err != nil {
+		t.Fatalf("Add timerfd failed: %v", err)
+	}
+	if err := ep.Add(efd, iofd.EPOLLIN, 0); err != zcall.EEXIST {
+		t.Errorf("Add twice: expected EEXIST, got %v", err)
+	}
+
+	events := make([]iofd.EpollEvent, 4)
+	if n, err := ep.Wait(events, 0); n != 0 || err != nil {
+		t.Errorf("Wait with nothing ready = (%d, %v), want (0, nil)", n, err)
+	}
+	// The timeout has sub-millisecond resolution
+	start := time.Now()
+	if n, err := ep.Wait(events, 1500*time.Microsecond); n != 0 || err != nil {
+		t.Errorf("Wait until timeout = (%d, %v), want (0, nil)", n, err)
+	}
+	if elapsed := time.Since(start); elapsed < 1500*time.Microsecond {
+		t.Errorf("Wait returned after %v, before its timeout", elapsed)
+	}
+
+	if err := tfd.ArmDuration(time.Millisecond, 0); err != nil {
+		t.Fatalf("ArmDuration failed: %v", err)
+	}
+	if n, err := ep.Wait(events, -1); n != 1 || err != nil {
+		t.Fatalf("Wait for timer = (%d, %v), want (1, nil)", n, err)
+	}
+	if events[0].UserData() != math.MaxUint64 || events[0].Events != iofd.EPOLLIN {
+		t.Errorf("timer event = %#x data %#x", events[0].Events, events[0].UserData())
+	}
+	_, _ = tfd.Read()
+
+	if err := ep.Modify(efd, iofd.EPOLLOUT, 43); err != nil {
+		t.Fatalf("Modify failed: %v", err)
+	}
+	if n, err := ep.Wait(events, 0); n != 1 || err != nil || events[0].UserData() != 43 || events[0].Events != iofd.EPOLLOUT {
+		t.Errorf("Wait after Modify = (%d, %v), event %#x data %d", n, err, events[0].Events, events[0].UserData())
+	}
+	if err := ep.Delete(efd); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if n, err := ep.Wait(events, 0); n != 0 || err != nil {
+		t.Errorf("Wait after Delete = (%d, %v), want (0, nil)", n, err)
+	}
+	if err := ep.Delete(efd); err != zcall.ENOENT {
+		t.Errorf("Delete twice: expected ENOENT, got %v", err)
+	}
+	if err := ep.Modify(efd, iofd.EPOLLIN, 0); err != zcall.ENOENT {
+		t.Errorf("Modify unregistered: expected ENOENT, got %v", err)
+	}
+
+	if _, err := ep.Wait(nil, 0); err != iofd.ErrInvalidParam {
+		t.Errorf("Wait without room: expected ErrInvalidParam, got %v", err)
+	}
+	efd.Close()
+	if err := ep.Add(efd, iofd.EPOLLIN, 0); err != iofd.ErrClosed {
+		t.Errorf("Add closed handle: expected ErrClosed, got %v", err)
+	}
+	ep.Close()
+	if _, err := ep.Wait(events, 0); err != iofd.ErrClosed {
+		t.Errorf("Wait after Close: expected ErrClosed, got %v", err)
+	}
+}
+
+func TestEpoll_Flags(t *testing.T) {
+	ep, err := iofd.NewEpoll()
+	if err != nil {
+		t.Fatalf("NewEpoll failed: %v", err)
+	}
+	defer ep.Close()
+	events := make([]iofd.EpollEvent, 1)
+	newEventFD := func() *iofd.EventFD {
+		efd, err := iofd.NewEventFD(0)
+		if err != nil {
+			t.Fatalf("NewEventFD failed: %v", err)
+		}
+		t.Cleanup(func() { efd.Close() })
+		return efd
+	}
+
+	t.Run("EPOLLET", func(t *testing.T) {
+		efd := newEventFD()
+		if err := ep.Add(efd, iofd.EPOLLIN|iofd.EPOLLET, 1); err != nil {
+			t.Fatalf("Add failed: %v", err)
+		}
+		defer ep.Delete(efd)
+		_ = efd.Signal(1)
+		if n, _ := ep.Wait(events, 0); n != 1 {
+			t.Errorf("first edge: %d events, want 1", n)
+		}
+		// Still readable, but no new edge
+		if n, _ := ep.Wait(events, 0); n != 0 {
+			t.Errorf("no edge: %d events, want 0", n)
+		}
+		_ = efd.Signal(1)
+		if n, _ := ep.Wait(events, 0); n != 1 {
+			t.Errorf("second edge: %d events, want 1", n)
+		}
+	})
+
+	t.Run("EPOLLONESHOT", func(t *testing.T) {
+		efd := newEventFD()
+		if err := ep.Add(efd, iofd.EPOLLIN|iofd.EPOLLONESHOT, 2); err != nil {
+			t.Fatalf("Add failed: %v", err)
+		}
+		defer ep.Delete(efd)
+		_ = efd.Signal(1)
+		if n, _ := ep.Wait(events, 0); n != 1 {
+			t.Errorf("first event: %d events, want 1", n)
+		}
+		_ = efd.Signal(1)
+		if n, _ := ep.Wait(events, 0); n != 0 {
+			t.Errorf("disarmed: %d events, want 0", n)
+		}
+		if err := ep.Modify(efd, iofd.EPOLLIN|iofd.EPOLLONESHOT, 2); err != nil {
+			t.Fatalf("Modify failed: %v", err)
+		}
+		if n, _ := ep.Wait(events, 0); n != 1 {
+			t.Errorf("rearmed: %d events, want 1", n)
+		}
+	})
+
+	t.Run("EPOLLEXCLUSIVE", func(t *testing.T) {
+		efd := newEventFD()
+		if err := ep.Add(efd, iofd.EPOLLIN|iofd.EPOLLEXCLUSIVE, 3); err != nil {
+			t.Fatalf("Add failed: %v", err)
+		}
+		defer ep.Delete(efd)
+		if err := ep.Modify(efd, iofd.EPOLLIN, 3); err != iofd.ErrInvalidParam {
+			t.Errorf("Modify exclusive: expected ErrInvalidParam, got %v", err)
+		}
+		_ = efd.Signal(1)
+		if n, _ := ep.Wait(events, 0); n != 1 || events[0].UserData() != 3 {
+			t.Errorf("exclusive event: %d events, data %d", n, events[0].UserData())
+		}
+	})
+
+	t.Run("EPOLLWAKEUP", func(t *testing.T) {
+		efd := newEventFD()
+		// Without CAP_BLOCK_SUSPEND the flag is silently dropped
+		if err := ep.Add(efd, iofd.EPOLLIN|iofd.EPOLLWAKEUP, 4); err != nil {
+			t.Fatalf("Add failed: %v", err)
+		}
+		defer ep.Delete(efd)
+		_ = efd.Signal(1)
+		if n, _ := ep.Wait(events, 0); n != 1 || events[0].UserData() != 4 {
+			t.Errorf("wakeup event: %d events, data %d", n, events[0].UserData())
+		}
+	})
+}
+
+func TestEpoll_Nested(t *testing.T) {
+	outer, err := iofd.NewEpoll()
+	if err != nil {
+		t.Fatalf("NewEpoll failed: %v", err)
+	}
+	defer outer.Close()
+	inner, err := iofd.NewEpoll()
+	if err != nil {
+		t.Fatalf("NewEpoll failed: %v", err)
+	}
+	defer inner.Close()
+	efd, err := iofd.NewEventFD(0)
+	if err != nil {
+		t.Fatalf("NewEventFD failed: %v", err)
+	}
+	defer efd.Close()
+
+	if err := inner.Add(efd, iofd.EPOLLIN, 1); err != nil {
+		t.Fatalf("inner Add failed: %v", err)
+	}
+	if err := outer.Add(inner, iofd.EPOLLIN, 2); err != nil {
+		t.Fatalf("outer Add failed: %v", err)
+	}
+	events := make([]iofd.EpollEvent, 2)
+	if n, err := outer.Wait(events, 0); n != 0 || err != nil {
+		t.Errorf("outer Wait with nothing ready = (%d, %v)", n, err)
+	}
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		_ = efd.Signal(1)
+	}()
+	if n, err := outer.Wait(events, 5*time.Second); n != 1 || err != nil || events[0].UserData() != 2 {
+		t.Fatalf("outer Wait = (%d, %v), data %d; want the inner epoll", n, err, events[0].UserData())
+	}
+	if n, err := inner.Wait(events, 0); n != 1 || err != nil || events[0].UserData() != 1 {
+		t.Errorf("inner Wait = (%d, %v), data %d; want the eventfd", n, err, events[0].UserData())
+	}
+	if !epollWaitReadable(t, outer.Fd(), 0) {
+		t.Error("outer epoll not readable while the eventfd is")
+	}
+}
+
+func TestEpoll_NoAlloc(t *testing.T) {
+	ep, err := iofd.NewEpoll()
+	if err != nil {
+		t.Fatalf("NewEpoll failed: %v", err)
+	}
+	defer ep.Close()
+	efd, err := iofd.NewEventFD(1)
+	if err != nil {
+		t.Fatalf("NewEventFD failed: %v", err)
+	}
+	defer efd.Close()
+	if err := ep.Add(efd, iofd.EPOLLIN, 1); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	events := make([]iofd.EpollEvent, 1)
+	allocs := testing.AllocsPerRun(100, func() {
+		_, _ = ep.Wait(events, time.Second)
+		_, _ = ep.Wait(events, -1, iofd.PollInterruptible)
+	})
+	if allocs != 0 {
+		t.Errorf("Wait allocated %v times per run, want 0", allocs)
+	}
+}
+
+func TestEpoll_ReleasesP(t *testing.T) {
+	ep, err := iofd.NewEpoll()
+	if err != nil {
+		t.Fatalf("NewEpoll failed: %v", err)
+	}
+	defer ep.Close()
+	efd, err := iofd.NewEventFD(0)
+	if err != nil {
+		t.Fatalf("NewEventFD failed: %v", err)
+	}
+	defer efd.Close()
+	if err := ep.Add(efd, iofd.EPOLLIN, 1); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	checkReleasesP(t, func() error {
+		events := make([]iofd.EpollEvent, 1)
+		_, err := ep.Wait(events, -1)
+		return err
+	}, func() { _ = efd.Signal(1) })
+}
+
+func TestEpoll_PWaitMask(t *testing.T) {
+	ep, err := iofd.NewEpoll()
+	if err != nil {
+		t.Fatalf("NewEpoll failed: %v", err)
+	}
+	defer ep.Close()
+
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, syscall.SIGUSR2)
+	defer signal.Stop(ch)
+	prev, err := iofd.BlockSignals(iofd.SigSetOf(iofd.SIGUSR2))
+	if err != nil {
+		t.Fatalf("BlockSignals failed: %v", err)
+	}
+	defer iofd.SetSignalMask(prev)
+	raise(t, iofd.SIGUSR2)
+
+	mask := prev
+	mask.Del(iofd.SIGUSR2)
+	events := make([]iofd.EpollEvent, 1)
+	if _, err := ep.PWait(events, 5*time.Second, &mask, iofd.PollInterruptible); err != iofd.ErrInterrupted {
+		t.Errorf("PWait with signal unblocked: expected ErrInterrupted, got %v", err)
+	}
+	select {
+	case <-ch:
+	case <-time.After(5 * time.Second):
+		t.Error("signal unblocked by PWait not delivered")
+	}
+}
+
+// =============================================================================
+// Loop Tests
+// =============================================================================
+
+func newTestLoop(t *testing.T) *iofd.Loop {
+	t.Helper()
+	l, err := iofd.NewLoop()
+	if err != nil {
+		t.Fatalf("NewLoop failed: %v", err)
+	}
+	t.Cleanup(func() { l.Close() })
+	return l
+}
+
+// runUntil runs l until done reports true, failing after five seconds.
+func runUntil(t *testing.T, l *iofd.Loop, done func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !done() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out running the loop")
+		}
+		if _, err := l.RunOnce(100 * time.Millisecond); err != nil {
+			t.Fatalf("RunOnce failed: %v", err)
+		}
+	}
+}
+
+func TestLoop_Handlers(t *testing.T) {
+	l := newTestLoop(t)
+	efd, err := iofd.NewEventFD(0)
+	if err != nil {
+		t.Fatalf("NewEventFD failed: %v", err)
+	}
+	tfd, err := iofd.NewTimerFD()
+	if err != nil {
+		t.Fatalf("NewTimerFD failed: %v", err)
+	}
+	raw, err := iofd.NewEventFD(0)
+	if err != nil {
+		t.Fatalf("NewEventFD failed: %v", err)
+	}
+
+	var notified, expirations uint64
+	var readable uint32
+	if err := l.OnNotify(efd, func(val uint64) { notified += val }); err != nil {
+		t.Fatalf("OnNotify failed: %v", err)
+	}
+	if err := l.OnTimer(tfd, func(n uint64) { expirations += n }); err != nil {
+		t.Fatalf("OnTimer failed: %v", err)
+	}
+	if err := l.OnReadable(raw, func(events uint32) {
+		readable = events
+		_, _ = raw.Wait()
+	}); err != nil {
+		t.Fatalf("OnReadable failed: %v", err)
+	}
+	if l.Len() != 3 {
+		t.Errorf("Len = %d, want 3", l.Len())
+	}
+	if n, err := l.RunOnce(0); n != 0 || err != nil {
+		t.Errorf("RunOnce with nothing ready = (%d, %v), want (0, nil)", n, err)
+	}
+
+	_ = efd.Signal(2)
+	_ = efd.Signal(3)
+	_ = raw.Signal(1)
+	if err := tfd.ArmDuration(time.Millisecond, time.Millisecond); err != nil {
+		t.Fatalf("ArmDuration failed: %v", err)
+	}
+	runUntil(t, l, func() bool { return expirations >= 3 })
+	if notified != 5 {
+		t.Errorf("notified %d, want 5", notified)
+	}
+	if readable != iofd.EPOLLIN {
+		t.Errorf("OnReadable events = %#x, want EPOLLIN", readable)
+	}
+}
+
+func TestLoop_OnSignal(t *testing.T) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	l := newTestLoop(t)
+	sfd := newThreadSignalFD(t, iofd.SIGUSR1, iofd.SIGUSR2)
+
+	var got []int
+	if err := l.OnSignal(sfd, func(info *iofd.SignalInfo) { got = append(got, int(info.Signo)) }); err != nil {
+		t.Fatalf("OnSignal failed: %v", err)
+	}
+	tgkill(t, sfd, iofd.SIGUSR1)
+	tgkill(t, sfd, iofd.SIGUSR2)
+	runUntil(t, l, func() bool { return len(got) == 2 })
+	slices.Sort(got)
+	if got[0] != iofd.SIGUSR1 || got[1] != iofd.SIGUSR2 {
+		t.Errorf("signals = %v, want SIGUSR1 and SIGUSR2", got)
+	}
+}
+
+func TestLoop_OnExit(t *testing.T) {
+	l := newTestLoop(t)
+	cmd := helperCommand(t, "exit", "3")
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	// Fails harmlessly once the loop has reaped the child
+	t.Cleanup(func() { _ = cmd.Wait() })
+	pfd, err := iofd.NewPidFD(cmd.Process.Pid)
+	if err != nil {
+		cmd.Process.Kill()
+		t.Fatalf("NewPidFD failed: %v", err)
+	}
+
+	calls := 0
+	var status iofd.ExitStatus
+	if err := l.OnExit(pfd, func(s iofd.ExitStatus, err error) {
+		calls++
+		status = s
+		if err != nil {
+			t.Errorf("OnExit error: %v", err)
+		}
+	}); err != nil {
+		t.Fatalf("OnExit failed: %v", err)
+	}
+	runUntil(t, l, func() bool { return calls > 0 })
+	if status.Code != iofd.CLD_EXITED || status.Status != 3 {
+		t.Errorf("status = %v, want exit status 3", status)
+	}
+	// The child has been reaped and the pidfd released
+	if pfd.Valid() || l.Len() != 0 {
+		t.Errorf("after exit: pidfd valid %v, Len %d", pfd.Valid(), l.Len())
+	}
+	if n, err := l.RunOnce(20 * time.Millisecond); n != 0 || err != nil || calls != 1 {
+		t.Errorf("RunOnce after exit = (%d, %v), calls %d", n, err, calls)
+	}
+}
+
+func TestLoop_WakeStop(t *testing.T) {
+	l := newTestLoop(t)
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		_ = l.Wake()
+	}()
+	if n, err := l.RunOnce(-1); n != 0 || err != nil {
+		t.Errorf("RunOnce woken = (%d, %v), want (0, nil)", n, err)
+	}
+
+	errc := make(chan error, 1)
+	go func() { errc <- l.Run(context.Background()) }()
+	time.Sleep(10 * time.Millisecond)
+	if err := l.Stop(); err != nil {
+		t.Fatalf("Stop failed: %v", err)
+	}
+	select {
+	case err := <-errc:
+		if err != nil {
+			t.Errorf("Run after Stop = %v, want nil", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not return after Stop")
+	}
+
+	// The loop can be run again, until its context is done
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := l.Run(ctx); err != context.DeadlineExceeded {
+		t.Errorf("Run with deadline: expected DeadlineExceeded, got %v", err)
+	}
+}
+
+func TestLoop_Close(t *testing.T) {
+	l := newTestLoop(t)
+	efd, err := iofd.NewEventFD(0)
+	if err != nil {
+		t.Fatalf("NewEventFD failed: %v", err)
+	}
+	tfd, err := iofd.NewTimerFD()
+	if err != nil {
+		t.Fatalf("NewTimerFD failed: %v", err)
+	}
+	kept, err := iofd.NewEventFD(0)
+	if err != nil {
+		t.Fatalf("NewEventFD failed: %v", err)
+	}
+	defer kept.Close()
+
+	_ = l.OnNotify(efd, func(uint64) {})
+	_ = l.OnTimer(tfd, func(uint64) {})
+	_ = l.OnNotify(kept, func(uint64) {})
+	if err := l.OnNotify(kept, func(uint64) {}); err != zcall.EEXIST {
+		t.Errorf("registering twice: expected EEXIST, got %v", err)
+	}
+	if err := l.Remove(kept); err != nil {
+		t.Fatalf("Remove failed: %v", err)
+	}
+	if err := l.Remove(kept); err != zcall.ENOENT {
+		t.Errorf("Remove twice: expected ENOENT, got %v", err)
+	}
+
+	// A Close from another goroutine stops Run, which shuts the loop down
+	errc := make(chan error, 1)
+	go func() { errc <- l.Run(context.Background()) }()
+	time.Sleep(10 * time.Millisecond)
+	if err := l.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	select {
+	case err := <-errc:
+		if err != nil {
+			t.Errorf("Run after Close = %v, want nil", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not return after Close")
+	}
+	if efd.Fd() >= 0 || tfd.Fd() >= 0 || l.Fd() >= 0 {
+		t.Error("registered handles and loop not closed")
+	}
+	if kept.Fd() < 0 {
+		t.Error("removed handle closed by the loop")
+	}
+	if err := l.OnNotify(kept, func(uint64) {}); err != iofd.ErrClosed {
+		t.Errorf("OnNotify after Close: expected ErrClosed, got %v", err)
+	}
+	if _, err := l.RunOnce(0); err != iofd.ErrClosed {
+		t.Errorf("RunOnce after Close: expected ErrClosed, got %v", err)
+	}
+	if err := l.Close(); err != nil {
+		t.Errorf("second Close = %v, want nil", err)
+	}
+}
+
+func TestLoop_ClosedHandle(t *testing.T) {
+	l := newTestLoop(t)
+	old, err := iofd.NewEventFD(0)
+	if err != nil {
+		t.Fatalf("NewEventFD failed: %v", err)
+	}
+	if err := l.OnNotify(old, func(uint64) {}); err != nil {
+		t.Fatalf("OnNotify failed: %v", err)
+	}
+	num := old.Fd()
+	old.Close()
+
+	// The descriptor number is reused by the next descriptor opened
+	reused, err := iofd.NewEventFD(0)
+	if err != nil {
+		t.Fatalf("NewEventFD failed: %v", err)
+	}
+	if reused.Fd() != num {
+		reused.Close()
+		t.Skipf("descriptor %d not reused", num)
+	}
+	var notified uint64
+	if err := l.OnNotify(reused, func(val uint64) { notified += val }); err != nil {
+		t.Fatalf("OnNotify of a reused descriptor number failed: %v", err)
+	}
+
+	// Removing the closed handle leaves the new registration intact
+	if err := l.Remove(old); err != nil {
+		t.Fatalf("Remove of a closed handle failed: %v", err)
+	}
+	if l.Len() != 1 {
+		t.Errorf("Len = %d, want 1", l.Len())
+	}
+	_ = reused.Signal(4)
+	runUntil(t, l, func() bool { return notified == 4 })
+}
+
+// sliceHandle is a PollFd whose dynamic type is not comparable.
+type sliceHandle struct {
+	fds []int
+}
+
+func (h sliceHandle) Fd() int { return h.fds[0] }
+
+func TestLoop_NonComparableHandle(t *testing.T) {
+	l := newTestLoop(t)
+	efd, err := iofd.NewEventFD(0)
+	if err != nil {
+		t.Fatalf("NewEventFD failed: %v", err)
+	}
+	defer efd.Close()
+	h := sliceHandle{fds: []int{efd.Fd()}}
+	if err := l.OnReadable(h, func(uint32) {}); err != iofd.ErrInvalidParam {
+		t.Errorf("OnReadable of a non-comparable handle = %v, want ErrInvalidParam", err)
+	}
+	if err := l.Remove(h); err != zcall.ENOENT {
+		t.Errorf("Remove of a non-comparable handle = %v, want ENOENT", err)
+	}
+	if l.Len() != 0 {
+		t.Errorf("Len = %d, want 0", l.Len())
+	}
+}
+
+func TestLoop_BlockingHandle(t *testing.T) {
+	l := newTestLoop(t)
+	efd, err := iofd.NewEventFDBlocking(0)
+	if err != nil {
+		t.Fatalf("NewEventFDBlocking failed: %v", err)
+	}
+	var notified uint64
+	if err := l.OnNotify(efd, func(val uint64) { notified += val }); err != nil {
+		t.Fatalf("OnNotify failed: %v", err)
+	}
+	_ = efd.Signal(2)
+	runUntil(t, l, func() bool { return notified == 2 })
+	// The drained eventfd leaves the loop idle
+	if n, err := l.RunOnce(0); n != 0 || err != nil {
+		t.Errorf("RunOnce on empty blocking eventfd = (%d, %v), want (0, nil)", n, err)
+	}
+}
+
+func TestLoop_ReleasesP(t *testing.T) {
+	l := newTestLoop(t)
+	checkReleasesP(t, func() error {
+		return l.Run(context.Background())
+	}, func() { _ = l.Stop() })
+}
+
+func TestLoop_CloseFromCallback(t *testing.T) {
+	l := newTestLoop(t)
+	first, err := iofd.NewEventFD(1)
+	if err != nil {
+		t.Fatalf("NewEventFD failed: %v", err)
+	}
+	second, err := iofd.NewEventFD(1)
+	if err != nil {
+		t.Fatalf("NewEventFD failed: %v", err)
+	}
+
+	ran := 0
+	callback := func(uint64) {
+		ran++
+		// Nested runs are refused
+		if _, err := l.RunOnce(0); err != iofd.ErrInvalidParam {
+			t.Errorf("nested RunOnce: expected ErrInvalidParam, got %v", err)
+		}
+		_ = l.Close()
+	}
+	_ = l.OnNotify(first, callback)
+	_ = l.OnNotify(second, callback)
+
+	if _, err := l.RunOnce(time.Second); err != nil {
+		t.Errorf("RunOnce closed by its callback = %v, want nil", err)
+	}
+	// Both eventfds were ready, but no callback runs after Close
+	if ran != 1 {
+		t.Errorf("%d callbacks ran, want 1", ran)
+	}
+	if first.Fd() >= 0 || second.Fd() >= 0 {
+		t.Error("handles not closed by the callback's Close")
+	}
+}
@@ -0,0 +1,482 @@
+// ©Hayabusa Cloud Co., Ltd. 2025. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+//go:build linux
+
+package iofd
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"reflect"
+	"slices"
+	"sync"
+	"time"
+
+	"code.hybscloud.com/iox"
+	"code.hybscloud.com/zcall"
+)
+
+// Loop is a minimal reactor: it waits on an Epoll and runs callbacks for
+// the registered handles that become readable.
+//
+// Callbacks run one at a time on the goroutine calling Run or RunOnce, and
+// may register and remove handles or stop and close the loop. Other
+// goroutines interrupt a waiting loop with Wake.
+//
+// The loop owns registered handles: Close closes those that implement
+// PollCloser, in registration order, and Remove hands a handle back
+// without closing it.
+type Loop struct {
+	mu       sync.Mutex
+	ep       *Epoll
+	wake     *Notifier
+	handlers map[uint64]*loopHandler
+	tokens   map[PollFd]uint64 // Token by handle
+	next     uint64
+	running  bool
+	stop     bool
+	closing  bool // Close was called while running
+	closed   bool
+}
+
+// loopHandler is a registered handle. dispatch handles its events and
+// reports whether the handle is done and must be removed and closed.
+type loopHandler struct {
+	fd       PollFd
+	dispatch func(events uint32) (done bool, err error)
+}
+
+// Epoll user data of the loop's own descriptors; handles use the
+// following values in registration order.
+const (
+	loopTokenWake = iota
+	loopTokenContext
+	loopTokenFirst
+)
+
+// loopBatch is the number of events read by one wait.
+const loopBatch = 64
+
+// NewLoop creates an empty Loop.
+func NewLoop() (*Loop, error) {
+	ep, err := NewEpoll()
+	if err != nil {
+		return nil, err
+	}
+	wake, err := NewNotifier()
+	if err != nil {
+		_ = ep.Close()
+		return nil, err
+	}
+	if err := ep.Add(wake, EPOLLIN, loopTokenWake); err != nil {
+		_ = wake.Close()
+		_ = ep.Close()
+		return nil, err
+	}
+	return &Loop{
+		ep:       ep,
+		wake:     wake,
+		handlers: make(map[uint64]*loopHandler),
+		tokens:   make(map[PollFd]uint64),
+		next:     loopTokenFirst,
+	}, nil
+}
+
+// Fd returns the epoll descriptor, readable while events are pending, so
+// a Loop can be driven with RunOnce from another event loop.
+// Implements PollFd interface.
+func (l *Loop) Fd() int {
+	return l.ep.Fd()
+}
+
+// Close shuts the loop down: it closes the registered handles in
+// registration order, then the loop's own descriptors, and returns the
+// errors of those closes joined.
+//
+// If Run or RunOnce is in progress, Close only stops it: the running call
+// shuts the loop down once the current callback has returned, without
+// running further callbacks, and returns the errors instead.
+// Implements PollCloser interface.
+func (l *Loop) Close() error {
+	l.mu.Lock()
+	if l.closed || l.closing {
+		l.mu.Unlock()
+		return nil
+	}
+	if l.running {
+		l.closing = true
+		l.mu.Unlock()
+		return l.Wake()
+	}
+	l.closed = true
+	l.mu.Unlock()
+	return l.shutdown()
+}
+
+// shutdown closes all descriptors of a loop marked closed or closing.
+func (l *Loop) shutdown() error {
+	l.mu.Lock()
+	tokens := slices.Sorted(maps.Keys(l.handlers))
+	handlers := make([]*loopHandler, 0, len(tokens))
+	for _, token := range tokens {
+		handlers = append(handlers, l.handlers[token])
+	}
+	clear(l.handlers)
+	clear(l.tokens)
+	l.closed = true
+	l.mu.Unlock()
+
+	var errs []error
+	for _, h := range handlers {
+		if c, ok := h.fd.(PollCloser); ok {
+			errs = append(errs, c.Close())
+		}
+	}
+	errs = append(errs, l.wake.Close(), l.ep.Close())
+	return errors.Join(errs...)
+}
+
+// Wake interrupts a waiting Run or RunOnce. It is safe to call from any
+// goroutine; wakeups before the loop next waits are coalesced.
+func (l *Loop) Wake() error {
+	return l.wake.Notify()
+}
+
+// Stop makes Run return nil once the current callback has returned.
+// The loop stays open and can be run again.
+func (l *Loop) Stop() error {
+	l.mu.Lock()
+	l.stop = true
+	l.mu.Unlock()
+	return l.Wake()
+}
+
+// OnReadable registers fd and runs f with the ready events, e.g. EPOLLIN
+// or EPOLLHUP, whenever it is readable. The registration is
+// level-triggered: f must consume the readiness or remove fd, or it is
+// called again at once.
+//
+// Handles are identified by their PollFd value, which must be comparable,
+// such as a pointer, and not by descriptor number. A handle whose dynamic
+// type is not comparable is rejected with ErrInvalidParam.
+func (l *Loop) OnReadable(fd PollFd, f func(events uint32)) error {
+	if f == nil {
+		return ErrInvalidParam
+	}
+	return l.add(fd, func(events uint32) (bool, error) {
+		f(events)
+		return false, nil
+	})
+}
+
+// OnSignal registers s and runs f for each signal read from it.
+//
+// A signalfd only reports signals pending for the process and for the
+// thread reading it, so thread-directed signals reach the loop only if
+// it runs on that thread.
+func (l *Loop) OnSignal(s *SignalFD, f func(info *SignalInfo)) error {
+	if f == nil {
+		return ErrInvalidParam
+	}
+	return l.add(s, func(uint32) (bool, error) {
+		var infos [8]SignalInfo
+		for {
+			raw := s.fd.Raw()
+			if raw < 0 {
+				return false, ErrClosed
+			}
+			n, err := s.tryReadBatch(raw, infos[:])
+			if err == iox.ErrWouldBlock {
+				return false, nil
+			}
+			if err != nil {
+				return false, err
+			}
+			for i := range n {
+				f(&infos[i])
+			}
+		}
+	})
+}
+
+// OnTimer registers t and runs f with the number of expirations whenever
+// it expires. f receives 0 when a timer armed with ArmAtCancelOnSet is
+// canceled by a change of the realtime clock.
+func (l *Loop) OnTimer(t *TimerFD, f func(expirations uint64)) error {
+	if f == nil {
+		return ErrInvalidParam
+	}
+	return l.add(t, func(uint32) (bool, error) {
+		raw := t.fd.Raw()
+		if raw < 0 {
+			return false, ErrClosed
+		}
+		n, err := t.tryRead(raw)
+		switch err {
+		case nil, ErrClockChanged:
+			f(n)
+		case iox.ErrWouldBlock:
+		default:
+			return false, err
+		}
+		return false, nil
+	})
+}
+
+// OnExit registers p and runs f once with the exit status of the process
+// when it exits; a child is reaped. err is ErrProcessExited if the status
+// of a process that is not a child is unavailable. p is then removed from
+// the loop and closed.
+func (l *Loop) OnExit(p *PidFD, f func(status ExitStatus, err error)) error {
+	if f == nil {
+		return ErrInvalidParam
+	}
+	return l.add(p, func(uint32) (bool, error) {
+		raw := p.fd.Raw()
+		if raw < 0 {
+			return true, nil
+		}
+		f(p.exitStatus(raw))
+		return true, nil
+	})
+}
+
+// OnNotify registers e and runs f with the value read whenever it is
+// signaled.
+func (l *Loop) OnNotify(e *EventFD, f func(val uint64)) error {
+	if f == nil {
+		return ErrInvalidParam
+	}
+	return l.add(e, func(uint32) (bool, error) {
+		raw := e.fd.Raw()
+		if raw < 0 {
+			return false, ErrClosed
+		}
+		val, err := e.tryWait(raw)
+		switch err {
+		case nil:
+			f(val)
+		case iox.ErrWouldBlock:
+		default:
+			return false, err
+		}
+		return false, nil
+	})
+}
+
+// add registers fd with its dispatch function.
+func (l *Loop) add(fd PollFd, dispatch func(uint32) (bool, error)) error {
+	if !hashable(fd) {
+		return ErrInvalidParam
+	}
+	raw := fd.Fd()
+	if raw < 0 {
+		return ErrClosed
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed || l.closing {
+		return ErrClosed
+	}
+	if _, ok := l.tokens[fd]; ok {
+		return zcall.EEXIST
+	}
+	token := l.next
+	if err := l.ep.Add(fd, EPOLLIN, token); err != nil {
+		return err
+	}
+	l.next++
+	l.handlers[token] = &loopHandler{fd: fd, dispatch: dispatch}
+	l.tokens[fd] = token
+	return nil
+}
+
+// Remove unregisters fd without closing it. Its callback does not run
+// any more, even for events already read by the running loop. A handle
+// closed while registered can still be removed.
+// Returns zcall.ENOENT if fd is not registered.
+func (l *Loop) Remove(fd PollFd) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed {
+		return ErrClosed
+	}
+	if !hashable(fd) {
+		return zcall.ENOENT
+	}
+	token, ok := l.tokens[fd]
+	if !ok {
+		return zcall.ENOENT
+	}
+	return l.removeLocked(token)
+}
+
+func (l *Loop) removeLocked(token uint64) error {
+	h := l.handlers[token]
+	delete(l.handlers, token)
+	delete(l.tokens, h.fd)
+	raw := h.fd.Fd()
+	if raw < 0 {
+		// Closing the handle has unregistered it, and its number may
+		// belong to another handle by now
+		return nil
+	}
+	return epollCtl(l.ep.fd.Raw(), EPOLL_CTL_DEL, int32(raw), 0, 0)
+}
+
+// hashable reports whether fd can be used as a map key without panicking.
+func hashable(fd PollFd) bool {
+	return fd != nil && reflect.ValueOf(fd).Comparable()
+}
+
+// Len returns the number of registered handles.
+func (l *Loop) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.handlers)
+}
+
+// Run runs callbacks until Stop is called, ctx is done or a typed handler
+// fails to read its handle, and returns nil, ctx.Err() or that error. A
+// loop closed while running is shut down before Run returns.
+func (l *Loop) Run(ctx context.Context) (err error) {
+	if err := l.begin(); err != nil {
+		return err
+	}
+	defer func() { err = l.end(err) }()
+
+	cfd, err := newContextFD(ctx)
+	if err != nil {
+		return err
+	}
+	defer cfd.close()
+	if cfd != nil {
+		if err := epollCtl(l.ep.fd.Raw(), EPOLL_CTL_ADD, cfd.raw(), EPOLLIN, loopTokenContext); err != nil {
+			return err
+		}
+		defer epollCtl(l.ep.fd.Raw(), EPOLL_CTL_DEL, cfd.raw(), 0, 0)
+	}
+
+	var events [loopBatch]EpollEvent
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if l.stopping() {
+			return nil
+		}
+		if _, err := l.poll(events[:], -1); err != nil {
+			return err
+		}
+	}
+}
+
+// RunOnce waits up to timeout for events, runs their callbacks and
+// returns the number of callbacks run. A negative timeout waits
+// indefinitely; zero returns at once. Wake and Stop make a waiting
+// RunOnce return early.
+func (l *Loop) RunOnce(timeout time.Duration) (n int, err error) {
+	if err := l.begin(); err != nil {
+		return 0, err
+	}
+	defer func() { err = l.end(err) }()
+	var events [loopBatch]EpollEvent
+	return l.poll(events[:], timeout)
+}
+
+// begin marks the loop running.
+func (l *Loop) begin() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed || l.closing {
+		return ErrClosed
+	}
+	if l.running {
+		return ErrInvalidParam
+	}
+	l.running = true
+	return nil
+}
+
+// end marks the loop stopped and completes a Close called while it ran.
+func (l *Loop) end(err error) error {
+	l.mu.Lock()
+	l.running = false
+	l.stop = false
+	closing := l.closing
+	l.mu.Unlock()
+	if closing {
+		return errors.Join(err, l.shutdown())
+	}
+	return err
+}
+
+// stopping reports whether Stop or Close has been called.
+func (l *Loop) stopping() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.stop || l.closing
+}
+
+// poll waits once and dispatches the events read.
+func (l *Loop) poll(events []EpollEvent, timeout time.Duration) (int, error) {
+	n, err := l.ep.Wait(events, timeout)
+	if err != nil {
+		return 0, err
+	}
+	ran := 0
+	for i := range n {
+		token := events[i].UserData()
+		switch token {
+		case loopTokenWake:
+			_ = l.wake.Read()
+			continue
+		case loopTokenContext:
+			// Run checks the context before waiting again
+			continue
+		}
+		l.mu.Lock()
+		h := l.handlers[token]
+		stop := l.closing
+		l.mu.Unlock()
+		if h == nil || stop {
+			continue
+		}
+		done, err := h.dispatch(events[i].Events)
+		ran++
+		if err != nil {
+			return ran, err
+		}
+		if done {
+			if err := l.release(token, h); err != nil {
+				return ran, err
+			}
+		}
+	}
+	return ran, nil
+}
+
+// release removes and closes a handler that is done, unless a callback has
+// removed it already.
+func (l *Loop) release(token uint64, h *loopHandler) error {
+	l.mu.Lock()
+	if l.handlers[token] != h {
+		l.mu.Unlock()
+		return nil
+	}
+	_ = l.removeLocked(token)
+	l.mu.Unlock()
+	if c, ok := h.fd.(PollCloser); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// Compile-time interface assertions
+var (
+	_ PollFd     = (*Loop)(nil)
+	_ PollCloser = (*Loop)(nil)
+)
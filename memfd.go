@@ -124,10 +124,12 @@ func (m *MemFD) Size() (int64, error) {
 	return stat.size, nil
 }
 
-// statBuf is a minimal struct stat for extracting file size.
+// statBuf is a minimal struct stat for extracting file size and inode.
 // Layout matches Linux struct stat on amd64/arm64.
 type statBuf struct {
-	_    [48]byte // fields before st_size
+	dev  uint64   // st_dev at offset 0
+	ino  uint64   // st_ino at offset 8
+	_    [32]byte // fields between st_ino and st_size
 	size int64    // st_size at offset 48
 	_    [88]byte // remaining fields
 }
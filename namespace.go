@@ -0,0 +1,287 @@
+// ©Hayabusa Cloud Co., Ltd. 2025. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+//go:build linux
+
+package iofd
+
+import (
+	"errors"
+	"math/bits"
+	"runtime"
+	"unsafe"
+
+	"code.hybscloud.com/zcall"
+)
+
+// NamespaceType identifies a kind of Linux namespace.
+// Values are the CLONE_NEW* flags accepted by setns(2), so several
+// types can be combined into a mask for RunInNamespaces.
+type NamespaceType uint32
+
+// Namespace types.
+const (
+	NamespaceMount  NamespaceType = CLONE_NEWNS
+	NamespaceNet    NamespaceType = CLONE_NEWNET
+	NamespacePID    NamespaceType = CLONE_NEWPID
+	NamespaceUTS    NamespaceType = CLONE_NEWUTS
+	NamespaceIPC    NamespaceType = CLONE_NEWIPC
+	NamespaceUser   NamespaceType = CLONE_NEWUSER
+	NamespaceCgroup NamespaceType = CLONE_NEWCGROUP
+	NamespaceTime   NamespaceType = CLONE_NEWTIME
+)
+
+// namespaceAll is the union of all supported namespace types.
+const namespaceAll = NamespaceMount | NamespaceNet | NamespacePID | NamespaceUTS |
+	NamespaceIPC | NamespaceUser | NamespaceCgroup | NamespaceTime
+
+// String returns the namespace name as used in /proc/[pid]/ns/.
+// Masks and unknown values return "unknown".
+func (t NamespaceType) String() string {
+	switch t {
+	case NamespaceMount:
+		return "mnt"
+	case NamespaceNet:
+		return "net"
+	case NamespacePID:
+		return "pid"
+	case NamespaceUTS:
+		return "uts"
+	case NamespaceIPC:
+		return "ipc"
+	case NamespaceUser:
+		return "user"
+	case NamespaceCgroup:
+		return "cgroup"
+	case NamespaceTime:
+		return "time"
+	default:
+		return "unknown"
+	}
+}
+
+// pidfdNamespaceIoctl returns the PIDFD_GET_*_NAMESPACE request for t.
+// Returns 0 if t is not a single supported namespace type.
+func pidfdNamespaceIoctl(t NamespaceType) uintptr {
+	switch t {
+	case NamespaceMount:
+		return PIDFD_GET_MNT_NAMESPACE
+	case NamespaceNet:
+		return PIDFD_GET_NET_NAMESPACE
+	case NamespacePID:
+		return PIDFD_GET_PID_NAMESPACE
+	case NamespaceUTS:
+		return PIDFD_GET_UTS_NAMESPACE
+	case NamespaceIPC:
+		return PIDFD_GET_IPC_NAMESPACE
+	case NamespaceUser:
+		return PIDFD_GET_USER_NAMESPACE
+	case NamespaceCgroup:
+		return PIDFD_GET_CGROUP_NAMESPACE
+	case NamespaceTime:
+		return PIDFD_GET_TIME_NAMESPACE
+	default:
+		return 0
+	}
+}
+
+// NamespaceFD represents a Linux namespace file descriptor (nsfs).
+// It keeps the namespace alive while open and can be passed to setns(2).
+//
+// NamespaceFD is created with O_CLOEXEC by the kernel.
+type NamespaceFD struct {
+	fd FD
+}
+
+// Namespace opens the namespace of the given type that the process belongs to.
+// This uses the PIDFD_GET_*_NAMESPACE ioctls (Linux 6.11+), so the namespace
+// is resolved through the pidfd and is not subject to PID reuse races.
+//
+// For NamespacePID and NamespaceTime, the process's own namespace is returned,
+// not the one used for its future children.
+func (p *PidFD) Namespace(kind NamespaceType) (*NamespaceFD, error) {
+	req := pidfdNamespaceIoctl(kind)
+	if req == 0 {
+		return nil, ErrInvalidParam
+	}
+	raw := p.fd.Raw()
+	if raw < 0 {
+		return nil, ErrClosed
+	}
+	fd, errno := zcall.Syscall4(SYS_IOCTL, uintptr(raw), req, 0, 0)
+	if errno != 0 {
+		return nil, errFromErrno(errno)
+	}
+	return &NamespaceFD{fd: FD(fd)}, nil
+}
+
+// Fd returns the underlying file descriptor.
+// Implements PollFd interface.
+func (n *NamespaceFD) Fd() int {
+	return n.fd.Fd()
+}
+
+// Close closes the namespace file descriptor.
+// Implements PollCloser interface.
+func (n *NamespaceFD) Close() error {
+	return n.fd.Close()
+}
+
+// Type returns the namespace type using the NS_GET_NSTYPE ioctl.
+func (n *NamespaceFD) Type() (NamespaceType, error) {
+	raw := n.fd.Raw()
+	if raw < 0 {
+		return 0, ErrClosed
+	}
+	typ, errno := zcall.Syscall4(SYS_IOCTL, uintptr(raw), NS_GET_NSTYPE, 0, 0)
+	if errno != 0 {
+		return 0, errFromErrno(errno)
+	}
+	return NamespaceType(typ), nil
+}
+
+// Inode returns the namespace inode number.
+// Two namespace file descriptors refer to the same namespace if and only if
+// their inode numbers (on the nsfs device) are equal. This is the number
+// shown in /proc/[pid]/ns/ links, e.g. "net:[4026531840]".
+func (n *NamespaceFD) Inode() (uint64, error) {
+	raw := n.fd.Raw()
+	if raw < 0 {
+		return 0, ErrClosed
+	}
+	var stat statBuf
+	_, errno := zcall.Syscall4(zcall.SYS_FSTAT, uintptr(raw), uintptr(unsafe.Pointer(&stat)), 0, 0)
+	if errno != 0 {
+		return 0, errFromErrno(errno)
+	}
+	return stat.ino, nil
+}
+
+// Valid reports whether the namespace fd is still valid.
+func (n *NamespaceFD) Valid() bool {
+	return n.fd.Valid()
+}
+
+// RunInNamespaces runs fn on the calling goroutine with its OS thread moved
+// into the namespaces of the process referred to by pidfd.
+//
+// kinds is a mask of NamespaceType values; all of them are entered atomically
+// with a single setns(2) call on the pidfd. The thread is locked for the
+// duration of fn and its original namespaces are restored afterwards,
+// also if fn panics.
+// If restoring fails, the thread is left locked so that the Go runtime
+// terminates it when the goroutine exits instead of reusing it.
+//
+// The kernel refuses to move a multi-threaded process into another user
+// or mount namespace, so NamespaceUser and NamespaceMount generally fail
+// with ErrInvalidParam in a Go program. It refuses NamespaceTime in any
+// multi-threaded process, which every Go program is, so kinds including it
+// are rejected with ErrInvalidParam without calling setns(2). fn must not
+// start goroutines that expect to observe the entered namespaces.
+func RunInNamespaces(pidfd *PidFD, kinds NamespaceType, fn func() error) (err error) {
+	if pidfd == nil || fn == nil || kinds == 0 || kinds&^namespaceAll != 0 {
+		return ErrInvalidParam
+	}
+	if kinds&NamespaceTime != 0 {
+		// setns(2) fails with EUSERS for a time namespace
+		return ErrInvalidParam
+	}
+	raw := pidfd.fd.Raw()
+	if raw < 0 {
+		return ErrClosed
+	}
+
+	runtime.LockOSThread()
+
+	// Save the current namespaces of this thread
+	var saved [8]struct {
+		fd   FD
+		kind NamespaceType
+	}
+	n := 0
+	closeSaved := func() {
+		for i := 0; i < n; i++ {
+			_ = saved[i].fd.Close()
+		}
+	}
+	for rest := uint32(kinds); rest != 0; rest &= rest - 1 {
+		kind := NamespaceType(1) << bits.TrailingZeros32(rest)
+		fd, err := openPath("/proc/thread-self/ns/"+kind.String(), O_RDONLY)
+		if err != nil {
+			closeSaved()
+			runtime.UnlockOSThread()
+			return err
+		}
+		saved[n].fd, saved[n].kind = fd, kind
+		n++
+	}
+
+	_, errno := zcall.Syscall4(SYS_SETNS, uintptr(raw), uintptr(kinds), 0, 0)
+	if errno != 0 {
+		closeSaved()
+		runtime.UnlockOSThread()
+		return errFromErrno(errno)
+	}
+
+	// Restore even if fn panics. The user namespace, if any, is restored
+	// first: joining the other original namespaces requires the
+	// capabilities held in it.
+	defer func() {
+		var rerr error
+		restore := func(user bool) {
+			for i := 0; i < n; i++ {
+				if (saved[i].kind == NamespaceUser) != user {
+					continue
+				}
+				_, errno := zcall.Syscall4(SYS_SETNS, uintptr(saved[i].fd.Raw()), uintptr(saved[i].kind), 0, 0)
+				if errno != 0 && rerr == nil {
+					rerr = errFromErrno(errno)
+				}
+			}
+		}
+		restore(true)
+		restore(false)
+		closeSaved()
+		if rerr == nil {
+			runtime.UnlockOSThread()
+		}
+		err = errors.Join(err, rerr)
+	}()
+	return fn()
+}
+
+// Namespace clone flags
+const (
+	CLONE_NEWTIME   = 0x80
+	CLONE_NEWNS     = 0x20000
+	CLONE_NEWCGROUP = 0x2000000
+	CLONE_NEWUTS    = 0x4000000
+	CLONE_NEWIPC    = 0x8000000
+	CLONE_NEWUSER   = 0x10000000
+	CLONE_NEWPID    = 0x20000000
+	CLONE_NEWNET    = 0x40000000
+)
+
+// pidfd ioctls for opening namespaces (_IO(0xFF, n))
+const (
+	PIDFD_GET_CGROUP_NAMESPACE = 0xFF01
+	PIDFD_GET_IPC_NAMESPACE    = 0xFF02
+	PIDFD_GET_MNT_NAMESPACE    = 0xFF03
+	PIDFD_GET_NET_NAMESPACE    = 0xFF04
+	PIDFD_GET_PID_NAMESPACE    = 0xFF05
+	PIDFD_GET_TIME_NAMESPACE   = 0xFF07
+	PIDFD_GET_USER_NAMESPACE   = 0xFF09
+	PIDFD_GET_UTS_NAMESPACE    = 0xFF0A
+)
+
+// nsfs ioctls (_IO(0xb7, n))
+const (
+	NS_GET_NSTYPE = 0xb703
+)
+
+// Compile-time interface assertions
+var (
+	_ PollFd     = (*NamespaceFD)(nil)
+	_ PollCloser = (*NamespaceFD)(nil)
+)
@@ -0,0 +1,229 @@
+// ©Hayabusa Cloud Co., Ltd. 2025. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+//go:build linux
+
+package iofd
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+
+	"code.hybscloud.com/iox"
+)
+
+// Notifier is a pollable wakeup that coalesces notifications, the
+// counterpart of a channel with a buffer of one used as a signal.
+//
+// A pending flag in memory suppresses the eventfd write while a
+// notification is already pending, so a burst of Notify calls costs a
+// single syscall. Fd becomes readable while a notification is pending.
+type Notifier struct {
+	efd     *EventFD
+	pending atomic.Bool
+}
+
+// NewNotifier creates a Notifier without a pending notification.
+func NewNotifier() (*Notifier, error) {
+	efd, err := NewEventFD(0)
+	if err != nil {
+		return nil, err
+	}
+	return &Notifier{efd: efd}, nil
+}
+
+// Fd returns the underlying eventfd.
+// Implements PollFd interface.
+func (n *Notifier) Fd() int {
+	return n.efd.Fd()
+}
+
+// Close closes the eventfd.
+// Implements PollCloser interface.
+func (n *Notifier) Close() error {
+	return n.efd.Close()
+}
+
+// Notify makes a notification pending. It writes to the eventfd only if
+// none was pending already.
+func (n *Notifier) Notify() error {
+	if !n.pending.CompareAndSwap(false, true) {
+		return nil
+	}
+	if err := n.efd.Signal(1); err != nil {
+		n.pending.Store(false)
+		return err
+	}
+	return nil
+}
+
+// Pending reports whether a notification is pending.
+func (n *Notifier) Pending() bool {
+	return n.pending.Load()
+}
+
+// Read consumes the pending notification. Work published before a Notify
+// that Read has consumed must be handled after Read returns; Notify calls
+// racing with Read are then either consumed with it or make the descriptor
+// readable again.
+// Returns iox.ErrWouldBlock if no notification is pending.
+func (n *Notifier) Read() error {
+	if _, err := n.efd.Wait(); err != nil {
+		return err
+	}
+	n.pending.Store(false)
+	return nil
+}
+
+// Latch is a pollable one-shot event. Once fired, its descriptor stays
+// readable: every current and future waiter is released, in any process
+// sharing the descriptor.
+type Latch struct {
+	efd   *EventFD
+	fired atomic.Bool
+}
+
+// NewLatch creates a Latch that has not fired.
+func NewLatch() (*Latch, error) {
+	efd, err := NewEventFD(0)
+	if err != nil {
+		return nil, err
+	}
+	return &Latch{efd: efd}, nil
+}
+
+// Fd returns the underlying eventfd.
+// Implements PollFd interface.
+func (l *Latch) Fd() int {
+	return l.efd.Fd()
+}
+
+// Close closes the eventfd.
+// Implements PollCloser interface.
+func (l *Latch) Close() error {
+	return l.efd.Close()
+}
+
+// Fire fires the latch. Calls after the first are no-ops.
+func (l *Latch) Fire() error {
+	if !l.fired.CompareAndSwap(false, true) {
+		return nil
+	}
+	if err := l.efd.Signal(1); err != nil {
+		l.fired.Store(false)
+		return err
+	}
+	return nil
+}
+
+// Fired reports whether the latch has fired, including by another process
+// sharing the descriptor.
+func (l *Latch) Fired() bool {
+	if l.fired.Load() {
+		return true
+	}
+	raw := l.efd.fd.Raw()
+	return raw >= 0 && ready(raw, POLLIN)
+}
+
+// Wait waits until the latch has fired or ctx is done.
+func (l *Latch) Wait(ctx context.Context) error {
+	return waitReadable(ctx, l.efd.fd.Raw())
+}
+
+// WaitGroup waits for a collection of tasks to finish, the pollable
+// counterpart of sync.WaitGroup: its descriptor is readable while the
+// counter is zero.
+type WaitGroup struct {
+	mu    sync.Mutex
+	efd   *EventFD
+	count int64
+}
+
+// NewWaitGroup creates a WaitGroup with a zero counter.
+func NewWaitGroup() (*WaitGroup, error) {
+	efd, err := NewEventFD(1)
+	if err != nil {
+		return nil, err
+	}
+	return &WaitGroup{efd: efd}, nil
+}
+
+// Fd returns the underlying eventfd.
+// Implements PollFd interface.
+func (wg *WaitGroup) Fd() int {
+	return wg.efd.Fd()
+}
+
+// Close closes the eventfd.
+// Implements PollCloser interface.
+func (wg *WaitGroup) Close() error {
+	return wg.efd.Close()
+}
+
+// Add adds delta, which may be negative, to the counter. The descriptor
+// stops being readable when the counter leaves zero and becomes readable
+// when it returns to zero. Returns ErrInvalidParam, leaving the counter
+// unchanged, if it would become negative.
+func (wg *WaitGroup) Add(delta int) error {
+	wg.mu.Lock()
+	defer wg.mu.Unlock()
+	count := wg.count + int64(delta)
+	if count < 0 {
+		return ErrInvalidParam
+	}
+	switch {
+	case wg.count == 0 && count > 0:
+		if _, err := wg.efd.Wait(); err != nil && err != iox.ErrWouldBlock {
+			return err
+		}
+	case wg.count > 0 && count == 0:
+		if err := wg.efd.Signal(1); err != nil {
+			return err
+		}
+	}
+	wg.count = count
+	return nil
+}
+
+// Done decrements the counter by one.
+func (wg *WaitGroup) Done() error {
+	return wg.Add(-1)
+}
+
+// Count returns the current counter.
+func (wg *WaitGroup) Count() int {
+	wg.mu.Lock()
+	defer wg.mu.Unlock()
+	return int(wg.count)
+}
+
+// Wait waits until the counter is zero or ctx is done.
+func (wg *WaitGroup) Wait(ctx context.Context) error {
+	return waitReadable(ctx, wg.efd.fd.Raw())
+}
+
+// waitReadable waits until raw is readable without consuming anything.
+func waitReadable(ctx context.Context, raw int32) error {
+	if raw < 0 {
+		return ErrClosed
+	}
+	return waitContext(ctx, raw, POLLIN, func() error {
+		if ready(raw, POLLIN) {
+			return nil
+		}
+		return iox.ErrWouldBlock
+	})
+}
+
+// Compile-time interface assertions
+var (
+	_ PollFd     = (*Notifier)(nil)
+	_ PollCloser = (*Notifier)(nil)
+	_ PollFd     = (*Latch)(nil)
+	_ PollCloser = (*Latch)(nil)
+	_ PollFd     = (*WaitGroup)(nil)
+	_ PollCloser = (*WaitGroup)(nil)
+)
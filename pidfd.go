@@ -7,6 +7,10 @@
 package iofd
 
 import (
+	"strconv"
+	"unsafe"
+
+	"code.hybscloud.com/iox"
 	"code.hybscloud.com/zcall"
 )
 
@@ -20,8 +24,9 @@ import (
 //   - The pidfd refers to a specific process instance, not just a PID.
 //   - After the process exits, the pidfd remains valid for signal/wait operations.
 type PidFD struct {
-	fd  FD
-	pid int
+	fd       FD
+	pid      int
+	blocking bool // Opened without PIDFD_NONBLOCK
 }
 
 // NewPidFD creates a new pidfd for the specified process ID.
@@ -46,7 +51,7 @@ func newPidFD(pid int, flags uintptr) (*PidFD, error) {
 	if errno != 0 {
 		return nil, errFromErrno(errno)
 	}
-	return &PidFD{fd: FD(fd), pid: pid}, nil
+	return &PidFD{fd: FD(fd), pid: pid, blocking: flags&PIDFD_NONBLOCK == 0}, nil
 }
 
 // Fd returns the underlying file descriptor.
@@ -110,11 +115,167 @@ func (p *PidFD) Valid() bool {
 	return p.fd.Valid()
 }
 
+// Wait waits for the process to exit and reaps it using waitid(P_PIDFD).
+// The process must be a child of the calling process.
+//
+// Returns iox.ErrWouldBlock if the pidfd is non-blocking and the process
+// is still running. For a pidfd created with NewPidFDBlocking, Wait parks
+// in ppoll(2) until the process exits, then reaps it without blocking. As
+// with Poll, the P of the calling goroutine is released while it waits.
+func (p *PidFD) Wait() (ExitStatus, error) {
+	if !p.blocking {
+		return p.wait(WEXITED)
+	}
+	fds := [1]PollEntry{{Fd: p.fd.Raw(), Events: POLLIN}}
+	for {
+		// Not waiting first reports a non-child at once
+		status, err := p.wait(WEXITED | WNOHANG)
+		if err != iox.ErrWouldBlock {
+			return status, err
+		}
+		if _, err := pollWait(fds[:]); err != nil {
+			return ExitStatus{}, err
+		}
+		if fds[0].Revents&POLLNVAL != 0 {
+			return ExitStatus{}, ErrClosed
+		}
+	}
+}
+
+// wait calls waitid(P_PIDFD) with the given options.
+// With WNOHANG, iox.ErrWouldBlock is returned if no state change is available.
+func (p *PidFD) wait(options uintptr) (ExitStatus, error) {
+	raw := p.fd.Raw()
+	if raw < 0 {
+		return ExitStatus{}, ErrClosed
+	}
+	var info waitInfo
+	_, errno := zcall.Syscall6(
+		SYS_WAITID,
+		P_PIDFD,
+		uintptr(raw),
+		uintptr(unsafe.Pointer(&info)),
+		options,
+		0, // no rusage
+		0,
+	)
+	if errno != 0 {
+		return ExitStatus{}, errFromErrno(errno)
+	}
+	// With WNOHANG, si_pid is zero if the child has not changed state
+	if info.pid == 0 {
+		return ExitStatus{}, iox.ErrWouldBlock
+	}
+	return ExitStatus{Code: info.code, Status: info.status}, nil
+}
+
+// waitInfo matches the leading fields of siginfo_t as filled by waitid.
+type waitInfo struct {
+	signo  int32
+	errno  int32
+	code   int32
+	_      int32
+	pid    int32
+	uid    uint32
+	status int32
+	_      [100]byte // Padding to 128 bytes
+}
+
+// ExitStatus describes how a child process changed state, as reported in
+// the si_code and si_status fields of siginfo for waitid and SIGCHLD.
+type ExitStatus struct {
+	Code   int32 // CLD_EXITED, CLD_KILLED, CLD_DUMPED, CLD_TRAPPED, CLD_STOPPED or CLD_CONTINUED
+	Status int32 // Exit code for CLD_EXITED, signal number otherwise
+}
+
+// Exited reports whether the process exited normally.
+func (s ExitStatus) Exited() bool {
+	return s.Code == CLD_EXITED
+}
+
+// ExitCode returns the exit code of a process that exited normally,
+// or -1 otherwise.
+func (s ExitStatus) ExitCode() int {
+	if !s.Exited() {
+		return -1
+	}
+	return int(s.Status)
+}
+
+// Signaled reports whether the process was terminated by a signal.
+func (s ExitStatus) Signaled() bool {
+	return s.Code == CLD_KILLED || s.Code == CLD_DUMPED
+}
+
+// CoreDumped reports whether the process was terminated by a signal
+// and dumped core.
+func (s ExitStatus) CoreDumped() bool {
+	return s.Code == CLD_DUMPED
+}
+
+// Stopped reports whether the process was stopped by a signal or trapped.
+func (s ExitStatus) Stopped() bool {
+	return s.Code == CLD_STOPPED || s.Code == CLD_TRAPPED
+}
+
+// Continued reports whether the process was resumed by SIGCONT.
+func (s ExitStatus) Continued() bool {
+	return s.Code == CLD_CONTINUED
+}
+
+// Signal returns the signal that terminated or stopped the process,
+// or 0 if the state change was not caused by a signal.
+func (s ExitStatus) Signal() int {
+	if s.Signaled() || s.Stopped() {
+		return int(s.Status)
+	}
+	return 0
+}
+
+// String returns a description in the style of os.ProcessState.
+func (s ExitStatus) String() string {
+	switch s.Code {
+	case CLD_EXITED:
+		return "exit status " + strconv.Itoa(int(s.Status))
+	case CLD_KILLED:
+		return "signal: " + strconv.Itoa(int(s.Status))
+	case CLD_DUMPED:
+		return "signal: " + strconv.Itoa(int(s.Status)) + " (core dumped)"
+	case CLD_TRAPPED, CLD_STOPPED:
+		return "stop signal: " + strconv.Itoa(int(s.Status))
+	case CLD_CONTINUED:
+		return "continued"
+	default:
+		return "unknown status " + strconv.Itoa(int(s.Code))
+	}
+}
+
 // pidfd flags
 const (
 	PIDFD_NONBLOCK = 0x800
 )
 
+// waitid idtype and options
+const (
+	P_PIDFD = 3
+
+	WNOHANG    = 0x1
+	WSTOPPED   = 0x2
+	WEXITED    = 0x4
+	WCONTINUED = 0x8
+	WNOWAIT    = 0x1000000
+)
+
+// SIGCHLD si_code values
+const (
+	CLD_EXITED    = 1
+	CLD_KILLED    = 2
+	CLD_DUMPED    = 3
+	CLD_TRAPPED   = 4
+	CLD_STOPPED   = 5
+	CLD_CONTINUED = 6
+)
+
 // Compile-time interface assertions
 var (
 	_ PollFd     = (*PidFD)(nil)
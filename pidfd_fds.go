@@ -0,0 +1,221 @@
+// ©Hayabusa Cloud Co., Ltd. 2025. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+//go:build linux
+
+package iofd
+
+import (
+	"strconv"
+	"strings"
+
+	"code.hybscloud.com/zcall"
+)
+
+// ProcessFD describes an open file descriptor of a process as seen in procfs.
+type ProcessFD struct {
+	Num    int    // Descriptor number in the target process
+	Target string // Link target, e.g. "anon_inode:[eventfd]" or "/memfd:name (deleted)"
+	Info   FDInfo // Parsed /proc/[pid]/fdinfo/[num]
+}
+
+// FDInfo holds the contents of /proc/[pid]/fdinfo/[num].
+type FDInfo struct {
+	Pos   int64  // File offset
+	Flags int    // Open file status flags
+	MntID int    // Mount ID
+	Ino   uint64 // Inode number
+	Raw   string // Complete fdinfo text, including type-specific fields
+}
+
+// Field returns the value of the named fdinfo field, such as "eventfd-count",
+// "clockid" or "sigmask". The second result reports whether the field exists.
+func (i FDInfo) Field(name string) (string, bool) {
+	for rest := i.Raw; rest != ""; {
+		var line string
+		line, rest, _ = strings.Cut(rest, "\n")
+		key, value, ok := strings.Cut(line, ":")
+		if ok && key == name {
+			return strings.TrimSpace(value), true
+		}
+	}
+	return "", false
+}
+
+// parseFDInfo parses the common fields of a fdinfo file.
+func parseFDInfo(raw string) FDInfo {
+	info := FDInfo{Raw: raw}
+	if v, ok := info.Field("pos"); ok {
+		info.Pos, _ = strconv.ParseInt(v, 10, 64)
+	}
+	if v, ok := info.Field("flags"); ok {
+		flags, _ := strconv.ParseUint(v, 8, 32)
+		info.Flags = int(flags)
+	}
+	if v, ok := info.Field("mnt_id"); ok {
+		info.MntID, _ = strconv.Atoi(v)
+	}
+	if v, ok := info.Field("ino"); ok {
+		info.Ino, _ = strconv.ParseUint(v, 10, 64)
+	}
+	return info
+}
+
+// ListFDs returns the open file descriptors of the process by reading
+// /proc/[pid]/fd and /proc/[pid]/fdinfo.
+//
+// Descriptors closed while the listing is in progress are omitted.
+// Liveness is verified through the pidfd before and after reading procfs;
+// ErrProcessExited is returned if the process has been reaped, since the
+// entries may then describe another process that reused the PID.
+//
+// Reading another process's descriptors requires
+// PTRACE_MODE_READ_FSCREDS permission.
+func (p *PidFD) ListFDs() ([]ProcessFD, error) {
+	raw := p.fd.Raw()
+	if raw < 0 {
+		return nil, ErrClosed
+	}
+	if err := p.checkAlive(raw); err != nil {
+		return nil, err
+	}
+
+	dir := "/proc/" + strconv.Itoa(p.pid)
+	names, err := readDirNames(dir + "/fd")
+	if err != nil {
+		return nil, p.procErr(raw, err)
+	}
+	fds := make([]ProcessFD, 0, len(names))
+	for _, name := range names {
+		num, err := strconv.Atoi(name)
+		if err != nil {
+			continue
+		}
+		target, err := readLink(dir + "/fd/" + name)
+		if err == zcall.ENOENT {
+			continue
+		}
+		if err != nil {
+			return nil, p.procErr(raw, err)
+		}
+		info, err := readFile(dir + "/fdinfo/" + name)
+		if err == zcall.ENOENT {
+			continue
+		}
+		if err != nil {
+			return nil, p.procErr(raw, err)
+		}
+		fds = append(fds, ProcessFD{Num: num, Target: target, Info: parseFDInfo(string(info))})
+	}
+
+	if err := p.checkAlive(raw); err != nil {
+		return nil, err
+	}
+	return fds, nil
+}
+
+// procErr prefers ErrProcessExited over a procfs error caused by the
+// process disappearing.
+func (p *PidFD) procErr(raw int32, err error) error {
+	if aerr := p.checkAlive(raw); aerr != nil {
+		return aerr
+	}
+	return err
+}
+
+// GetHandle duplicates a file descriptor from the target process like GetFD
+// and wraps it in the matching iofd handle type.
+//
+// The returned value is an *EventFD, *TimerFD, *SignalFD, *PidFD, *MemFD or
+// *NamespaceFD when the descriptor's kind is recognized, and a *FD otherwise.
+// Use a type switch to recover the concrete handle.
+func (p *PidFD) GetHandle(targetFD int) (PollCloser, error) {
+	fd, err := p.GetFD(targetFD)
+	if err != nil {
+		return nil, err
+	}
+	h, err := newHandle(fd)
+	if err != nil {
+		_ = fd.Close()
+		return nil, err
+	}
+	return h, nil
+}
+
+// newHandle wraps fd, owned by the current process, in the iofd handle
+// type matching its kind as reported by procfs.
+func newHandle(fd FD) (PollCloser, error) {
+	name := strconv.Itoa(int(fd.Raw()))
+	target, err := readLink("/proc/self/fd/" + name)
+	if err != nil {
+		return nil, err
+	}
+	info := func() (FDInfo, error) {
+		b, err := readFile("/proc/self/fdinfo/" + name)
+		if err != nil {
+			return FDInfo{}, err
+		}
+		return parseFDInfo(string(b)), nil
+	}
+
+	switch {
+	case target == "anon_inode:[eventfd]":
+		fi, err := info()
+		if err != nil {
+			return nil, err
+		}
+		return &EventFD{fd: fd, blocking: fi.Flags&O_NONBLOCK == 0}, nil
+	case target == "anon_inode:[timerfd]":
+		fi, err := info()
+		if err != nil {
+			return nil, err
+		}
+		v, _ := fi.Field("clockid")
+		clockid, _ := strconv.Atoi(v)
+		return &TimerFD{fd: fd, clockid: clockid, blocking: fi.Flags&O_NONBLOCK == 0}, nil
+	case target == "anon_inode:[signalfd]":
+		fi, err := info()
+		if err != nil {
+			return nil, err
+		}
+		v, _ := fi.Field("sigmask")
+		mask, _ := strconv.ParseUint(v, 16, 64)
+		return &SignalFD{fd: fd, mask: SigSet(mask), blocking: fi.Flags&O_NONBLOCK == 0}, nil
+	case target == "anon_inode:[pidfd]":
+		fi, err := info()
+		if err != nil {
+			return nil, err
+		}
+		v, _ := fi.Field("Pid")
+		pid, _ := strconv.Atoi(v)
+		return &PidFD{fd: fd, pid: pid, blocking: fi.Flags&O_NONBLOCK == 0}, nil
+	case strings.HasPrefix(target, "/memfd:"):
+		memName := strings.TrimSuffix(strings.TrimPrefix(target, "/memfd:"), " (deleted)")
+		return &MemFD{fd: fd, name: memName}, nil
+	case isNamespaceTarget(target):
+		return &NamespaceFD{fd: fd}, nil
+	default:
+		return &fd, nil
+	}
+}
+
+// isNamespaceTarget reports whether target has the form of a nsfs link,
+// e.g. "net:[4026531840]".
+func isNamespaceTarget(target string) bool {
+	kind, ino, ok := strings.Cut(target, ":[")
+	if !ok || !strings.HasSuffix(ino, "]") {
+		return false
+	}
+	switch kind {
+	case "mnt", "net", "pid", "uts", "ipc", "user", "cgroup", "time":
+		_, err := strconv.ParseUint(strings.TrimSuffix(ino, "]"), 10, 64)
+		return err == nil
+	}
+	return false
+}
+
+// Compile-time interface assertions
+var (
+	_ PollCloser = (*FD)(nil)
+)
@@ -0,0 +1,162 @@
+// ©Hayabusa Cloud Co., Ltd. 2025. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+//go:build linux
+
+package iofd
+
+import (
+	"unsafe"
+
+	"code.hybscloud.com/zcall"
+)
+
+// RemoteIovec describes a memory range in another process's address space.
+// This structure matches struct iovec from the Linux kernel.
+type RemoteIovec struct {
+	Base uintptr // Start address in the target process
+	Len  uint64  // Length of the range in bytes
+}
+
+// iovec matches struct iovec for local buffers.
+type iovec struct {
+	base unsafe.Pointer
+	len  uint64
+}
+
+// maxStackIovecs is the number of local iovecs built without allocation.
+const maxStackIovecs = 8
+
+// ReadMemory reads memory of the target process into local buffers
+// using process_vm_readv(2).
+//
+// remote describes the source ranges in the target process; the data is
+// scattered into local in order. Returns the number of bytes read, which
+// may be less than requested if a remote range is partially unmapped.
+//
+// process_vm_readv addresses the process by its numeric PID. To rule out
+// PID reuse, liveness is verified through the pidfd before and after the
+// transfer; ErrProcessExited is returned if the process has been reaped,
+// in which case any data read must be discarded.
+//
+// This operation requires PTRACE_MODE_ATTACH_REALCREDS permission over the
+// target process.
+func (p *PidFD) ReadMemory(remote []RemoteIovec, local [][]byte) (int, error) {
+	return p.transferMemory(SYS_PROCESS_VM_READV, remote, local)
+}
+
+// WriteMemory writes local buffers into memory of the target process
+// using process_vm_writev(2).
+//
+// remote describes the destination ranges in the target process. Returns
+// the number of bytes written. Liveness is verified through the pidfd
+// before and after the transfer as in ReadMemory.
+func (p *PidFD) WriteMemory(remote []RemoteIovec, local [][]byte) (int, error) {
+	return p.transferMemory(SYS_PROCESS_VM_WRITEV, remote, local)
+}
+
+func (p *PidFD) transferMemory(trap uintptr, remote []RemoteIovec, local [][]byte) (int, error) {
+	raw := p.fd.Raw()
+	if raw < 0 {
+		return 0, ErrClosed
+	}
+	if len(remote) == 0 || len(local) == 0 {
+		return 0, nil
+	}
+
+	var stack [maxStackIovecs]iovec
+	var liov []iovec
+	if len(local) <= len(stack) {
+		liov = stack[:len(local)]
+	} else {
+		liov = make([]iovec, len(local))
+	}
+	for i, b := range local {
+		if len(b) > 0 {
+			liov[i] = iovec{base: unsafe.Pointer(&b[0]), len: uint64(len(b))}
+		}
+	}
+
+	if err := p.checkAlive(raw); err != nil {
+		return 0, err
+	}
+	n, errno := zcall.Syscall6(
+		trap,
+		uintptr(p.pid),
+		uintptr(unsafe.Pointer(&liov[0])),
+		uintptr(len(liov)),
+		uintptr(unsafe.Pointer(&remote[0])),
+		uintptr(len(remote)),
+		0, // flags must be zero
+	)
+	if errno != 0 {
+		if zcall.Errno(errno) == zcall.ESRCH {
+			return 0, ErrProcessExited
+		}
+		return 0, errFromErrno(errno)
+	}
+	// The PID may have been reused by another process during the call
+	if err := p.checkAlive(raw); err != nil {
+		return int(n), err
+	}
+	return int(n), nil
+}
+
+// Madvise gives advice about the use of memory ranges of the target process
+// using process_madvise(2). Unlike ReadMemory, the process is addressed
+// through the pidfd itself, so this call is free of PID reuse races.
+//
+// advice must be one of MADV_COLD, MADV_PAGEOUT, MADV_WILLNEED or
+// MADV_COLLAPSE. Returns the number of bytes advised.
+//
+// This operation requires PTRACE_MODE_READ_FSCREDS permission and
+// CAP_SYS_NICE over the target process.
+func (p *PidFD) Madvise(remote []RemoteIovec, advice int) (int, error) {
+	raw := p.fd.Raw()
+	if raw < 0 {
+		return 0, ErrClosed
+	}
+	if len(remote) == 0 {
+		return 0, nil
+	}
+	n, errno := zcall.Syscall6(
+		SYS_PROCESS_MADVISE,
+		uintptr(raw),
+		uintptr(unsafe.Pointer(&remote[0])),
+		uintptr(len(remote)),
+		uintptr(advice),
+		0, // flags must be zero
+		0,
+	)
+	if errno != 0 {
+		if zcall.Errno(errno) == zcall.ESRCH {
+			return 0, ErrProcessExited
+		}
+		return 0, errFromErrno(errno)
+	}
+	return int(n), nil
+}
+
+// checkAlive verifies through the pidfd that the process has not been reaped.
+// A zombie still holds its PID, so it is reported as alive.
+func (p *PidFD) checkAlive(raw int32) error {
+	errno := zcall.PidfdSendSignal(uintptr(raw), 0, nil, 0)
+	switch zcall.Errno(errno) {
+	case 0, zcall.EPERM:
+		// EPERM still proves the process exists
+		return nil
+	case zcall.ESRCH:
+		return ErrProcessExited
+	default:
+		return errFromErrno(errno)
+	}
+}
+
+// madvise advice values accepted by process_madvise
+const (
+	MADV_WILLNEED = 3
+	MADV_COLD     = 20
+	MADV_PAGEOUT  = 21
+	MADV_COLLAPSE = 25
+)
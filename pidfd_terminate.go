@@ -0,0 +1,167 @@
+// ©Hayabusa Cloud Co., Ltd. 2025. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+//go:build linux
+
+package iofd
+
+import (
+	"context"
+	"time"
+	"unsafe"
+
+	"code.hybscloud.com/zcall"
+)
+
+// TerminateStep is one escalation step of a TerminatePolicy.
+type TerminateStep struct {
+	Signal int           // Signal to send, e.g. SIGTERM
+	Grace  time.Duration // Time to wait for exit before the next step; 0 waits without limit
+}
+
+// TerminatePolicy describes how Terminate escalates.
+//
+// Steps are applied in order: each sends its signal and waits up to its
+// grace period for the process to exit. The zero value sends SIGTERM,
+// waits up to 10 seconds, then sends SIGKILL and waits for exit.
+type TerminatePolicy struct {
+	Steps []TerminateStep
+}
+
+// defaultTerminateSteps is used by a TerminatePolicy without steps.
+var defaultTerminateSteps = [...]TerminateStep{
+	{Signal: SIGTERM, Grace: 10 * time.Second},
+	{Signal: SIGKILL},
+}
+
+// Terminate stops the process by sending the signals of policy in turn,
+// escalating whenever a grace period expires, and returns its final
+// ExitStatus.
+//
+// Exit is detected by polling the pidfd for readability, and grace periods
+// are measured with a TimerFD, so the numeric PID is never used. A child of
+// the calling process is reaped with waitid(P_PIDFD). For any other process,
+// or a child already reaped elsewhere, the status is retrieved with the
+// PIDFD_GET_INFO ioctl (Linux 6.15+) once the process has been reaped; if it
+// is not available, ErrProcessExited is returned with a zero ExitStatus.
+//
+// Returns ErrTimeout if the grace period of the last step expires, and the
+// context's error if ctx is done first.
+//
+// The calling goroutine blocks in ppoll(2) outside the runtime netpoller:
+// it keeps its OS thread for the whole wait, but, as with Poll, its P is
+// released to other goroutines.
+func (p *PidFD) Terminate(ctx context.Context, policy TerminatePolicy) (ExitStatus, error) {
+	raw := p.fd.Raw()
+	if raw < 0 {
+		return ExitStatus{}, ErrClosed
+	}
+	steps := policy.Steps
+	if len(steps) == 0 {
+		steps = defaultTerminateSteps[:]
+	}
+	for _, step := range steps {
+		if step.Signal <= 0 || step.Signal > 64 || step.Grace < 0 {
+			return ExitStatus{}, ErrInvalidParam
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return ExitStatus{}, err
+	}
+
+	timer, err := NewTimerFD()
+	if err != nil {
+		return ExitStatus{}, err
+	}
+	defer timer.Close()
+	cfd, err := newContextFD(ctx)
+	if err != nil {
+		return ExitStatus{}, err
+	}
+	defer cfd.close()
+
+	fds := [3]PollEntry{
+		{Fd: raw, Events: POLLIN},
+		{Fd: timer.fd.Raw(), Events: POLLIN},
+		{Fd: cfd.raw(), Events: POLLIN},
+	}
+	for _, step := range steps {
+		// ESRCH: the process has already been reaped and the pidfd is readable
+		if err := p.SendSignal(step.Signal); err != nil && err != zcall.ESRCH {
+			return ExitStatus{}, err
+		}
+		// Arming replaces any previous expiration; 0 disarms
+		if err := timer.Arm(int64(step.Grace), 0); err != nil {
+			return ExitStatus{}, err
+		}
+		if _, err := pollWait(fds[:]); err != nil {
+			return ExitStatus{}, err
+		}
+		// Exit takes precedence over a deadline reached at the same time
+		if fds[0].Revents != 0 {
+			return p.exitStatus(raw)
+		}
+		if fds[2].Revents != 0 {
+			return ExitStatus{}, ctx.Err()
+		}
+	}
+	return ExitStatus{}, ErrTimeout
+}
+
+// exitStatus returns the status of an exited process: a child is reaped
+// with waitid, otherwise the status is queried with PIDFD_GET_INFO.
+func (p *PidFD) exitStatus(raw int32) (ExitStatus, error) {
+	status, err := p.wait(WEXITED | WNOHANG)
+	if err != zcall.ECHILD {
+		return status, err
+	}
+
+	info := pidfdInfo{mask: PIDFD_INFO_EXIT}
+	_, errno := zcall.Syscall4(SYS_IOCTL, uintptr(raw), PIDFD_GET_INFO, uintptr(unsafe.Pointer(&info)), 0)
+	if errno != 0 || info.mask&PIDFD_INFO_EXIT == 0 {
+		// Not yet reaped by its parent, or not supported by the kernel
+		return ExitStatus{}, ErrProcessExited
+	}
+	return exitStatusFromWait(info.exitCode), nil
+}
+
+// exitStatusFromWait decodes a wait(2) status word of a terminated process.
+func exitStatusFromWait(ws int32) ExitStatus {
+	switch {
+	case ws&0x7f == 0:
+		return ExitStatus{Code: CLD_EXITED, Status: (ws >> 8) & 0xff}
+	case ws&0x80 != 0:
+		return ExitStatus{Code: CLD_DUMPED, Status: ws & 0x7f}
+	default:
+		return ExitStatus{Code: CLD_KILLED, Status: ws & 0x7f}
+	}
+}
+
+// pidfdInfo matches the first version of struct pidfd_info.
+type pidfdInfo struct {
+	mask     uint64
+	cgroupid uint64
+	pid      uint32
+	tgid     uint32
+	ppid     uint32
+	ruid     uint32
+	rgid     uint32
+	euid     uint32
+	egid     uint32
+	suid     uint32
+	sgid     uint32
+	fsuid    uint32
+	fsgid    uint32
+	exitCode int32
+}
+
+// pidfd info ioctl (_IOWR(0xFF, 11, struct pidfd_info)) and mask bits
+const (
+	PIDFD_GET_INFO = 0xC040FF0B
+
+	PIDFD_INFO_PID      = 0x1
+	PIDFD_INFO_CREDS    = 0x2
+	PIDFD_INFO_CGROUPID = 0x4
+	PIDFD_INFO_EXIT     = 0x8
+)
@@ -0,0 +1,312 @@
+// ©Hayabusa Cloud Co., Ltd. 2025. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+//go:build linux
+
+package iofd
+
+import (
+	"context"
+	"runtime"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"code.hybscloud.com/iox"
+	"code.hybscloud.com/zcall"
+)
+
+// PollEntry is an entry of the descriptor set passed to Poll and PPoll.
+// It matches struct pollfd, so a slice of entries is passed to the kernel
+// as is. POLLERR, POLLHUP and POLLNVAL are reported in Revents whether or
+// not they are requested.
+type PollEntry struct {
+	Fd      int32 // Descriptor to poll; entries with a negative Fd are skipped
+	Events  int16 // Requested events, e.g. POLLIN | POLLOUT
+	Revents int16 // Returned events, set by Poll
+}
+
+// PollEntryOf returns a PollEntry requesting events on fd.
+// A closed iofd handle yields an entry with a negative Fd, which is skipped.
+func PollEntryOf(fd PollFd, events int16) PollEntry {
+	return PollEntry{Fd: int32(fd.Fd()), Events: events}
+}
+
+// PollOption modifies the behavior of Poll and PPoll.
+type PollOption int
+
+const (
+	// PollInterruptible makes Poll and PPoll return ErrInterrupted when
+	// the wait is interrupted by a signal, instead of waiting again for
+	// the remaining timeout.
+	PollInterruptible PollOption = 1 << iota
+)
+
+// Poll waits until one of the descriptors in fds has a requested event
+// or timeout elapses, and returns the number of entries with non-zero
+// Revents. A negative timeout waits indefinitely; zero returns at once.
+// It does not allocate.
+//
+// A wait with a non-zero timeout enters the kernel as a blocking system
+// call: the calling goroutine keeps its OS thread, but its P is handed to
+// other goroutines while it waits. A wait interrupted by a signal is
+// resumed for the remaining timeout unless PollInterruptible is given.
+func Poll(fds []PollEntry, timeout time.Duration, opts ...PollOption) (int, error) {
+	return PPoll(fds, timeout, nil, opts...)
+}
+
+// PPoll is like Poll, but replaces the signal mask of the calling thread
+// with mask for the duration of the wait, as ppoll(2) does atomically.
+// A nil mask leaves the signal mask unchanged.
+//
+// Unblocking a signal in mask is only useful with PollInterruptible and a
+// thread-directed signal: with runtime.LockOSThread, a signal blocked
+// otherwise then interrupts exactly this wait.
+func PPoll(fds []PollEntry, timeout time.Duration, mask *SigSet, opts ...PollOption) (int, error) {
+	return retryInterrupted(timeout, opts, func(remaining int64) (int, error) {
+		var ts *timespec
+		if remaining >= 0 {
+			ts = new(timespec)
+			*ts = nsToTimespec(remaining)
+		}
+		return ppoll(fds, ts, mask)
+	})
+}
+
+// retryInterrupted calls wait with the nanoseconds left of timeout, or -1
+// for none, and calls it again after yielding whenever it fails with
+// ErrInterrupted, unless opts include PollInterruptible.
+func retryInterrupted(timeout time.Duration, opts []PollOption, wait func(remaining int64) (int, error)) (int, error) {
+	var flags PollOption
+	for _, opt := range opts {
+		flags |= opt
+	}
+	remaining := int64(-1)
+	var start time.Time
+	if timeout >= 0 {
+		remaining = int64(timeout)
+		start = time.Now()
+	}
+	for {
+		n, err := wait(remaining)
+		if err != ErrInterrupted || flags&PollInterruptible != 0 {
+			return n, err
+		}
+		runtime.Gosched()
+		if timeout >= 0 {
+			// time.Since uses the monotonic clock reading of start
+			remaining = max(int64(timeout-time.Since(start)), 0)
+		}
+	}
+}
+
+// ppoll calls ppoll(2) once. A nil timeout blocks until an event occurs;
+// a nil mask leaves the signal mask unchanged. Entries with a negative fd
+// are ignored by the kernel.
+//
+// A zero timeout is a probe and uses the raw zcall path. Any other wait
+// goes through syscall.Syscall6, whose entersyscall lets the runtime hand
+// the P to another goroutine and run a garbage collection meanwhile.
+func ppoll(fds []PollEntry, timeout *timespec, mask *SigSet) (int, error) {
+	var sigsetsize uintptr
+	if mask != nil {
+		sigsetsize = unsafe.Sizeof(*mask)
+	}
+	var n, errno uintptr
+	if timeout != nil && *timeout == (timespec{}) {
+		n, errno = zcall.Syscall6(
+			SYS_PPOLL,
+			uintptr(unsafe.Pointer(unsafe.SliceData(fds))),
+			uintptr(len(fds)),
+			uintptr(unsafe.Pointer(timeout)),
+			uintptr(unsafe.Pointer(mask)),
+			sigsetsize,
+			0,
+		)
+	} else {
+		var e syscall.Errno
+		n, _, e = syscall.Syscall6(
+			SYS_PPOLL,
+			uintptr(unsafe.Pointer(unsafe.SliceData(fds))),
+			uintptr(len(fds)),
+			uintptr(unsafe.Pointer(timeout)),
+			uintptr(unsafe.Pointer(mask)),
+			sigsetsize,
+			0,
+		)
+		errno = uintptr(e)
+	}
+	if errno != 0 {
+		return 0, errFromErrno(errno)
+	}
+	return int(n), nil
+}
+
+// pollWait blocks until an event occurs on fds, waiting again whenever a
+// signal interrupts it. The P is released while it blocks.
+func pollWait(fds []PollEntry) (int, error) {
+	return PPoll(fds, -1, nil)
+}
+
+// ready reports whether raw has any of events, or an error or hangup,
+// without blocking. A closed descriptor is never ready.
+func ready(raw int32, events int16) bool {
+	fds := [1]PollEntry{{Fd: raw, Events: events}}
+	var zero timespec
+	n, err := ppoll(fds[:], &zero, nil)
+	return err == nil && n > 0 && fds[0].Revents&POLLNVAL == 0
+}
+
+// readNow reads into buf from raw without blocking. A descriptor opened
+// without O_NONBLOCK, as indicated by blocking, is read with preadv2(2)
+// and RWF_NOWAIT, which fails with EAGAIN instead of blocking and leaves
+// the shared file description untouched. Where the kernel does not support
+// RWF_NOWAIT for the file type, it falls back to read(2) after a readiness
+// check, which can still block if another reader drains raw in between.
+func readNow(raw int32, buf []byte, blocking bool) (uintptr, uintptr) {
+	if !blocking {
+		return zcall.Read(uintptr(raw), buf)
+	}
+	iov := iovec{base: unsafe.Pointer(&buf[0]), len: uint64(len(buf))}
+	n, errno := zcall.Syscall6(
+		SYS_PREADV2,
+		uintptr(raw),
+		uintptr(unsafe.Pointer(&iov)),
+		1,
+		^uintptr(0), // Offset -1: the current file position
+		0,
+		RWF_NOWAIT,
+	)
+	if zcall.Errno(errno) != zcall.EOPNOTSUPP {
+		return n, errno
+	}
+	if !ready(raw, POLLIN) {
+		return 0, uintptr(zcall.EAGAIN)
+	}
+	return zcall.Read(uintptr(raw), buf)
+}
+
+// readWait reads into buf from raw and converts a failure with errFn,
+// which must map EAGAIN to iox.ErrWouldBlock. A descriptor opened without
+// O_NONBLOCK is not read with a blocking read(2), which would hide the
+// thread from the Go runtime and stall its stop-the-world phases; instead
+// the wait parks in ppoll(2), as waitContext does, before readNow.
+func readWait(raw int32, buf []byte, blocking bool, errFn func(uintptr) error) (int, error) {
+	if !blocking {
+		n, errno := zcall.Read(uintptr(raw), buf)
+		if errno != 0 {
+			return int(n), errFn(errno)
+		}
+		return int(n), nil
+	}
+	var n uintptr
+	err := waitContext(context.Background(), raw, POLLIN, func() error {
+		var errno uintptr
+		n, errno = readNow(raw, buf, true)
+		if errno != 0 {
+			return errFn(errno)
+		}
+		return nil
+	})
+	return int(n), err
+}
+
+// waitContext calls try until it returns something other than
+// iox.ErrWouldBlock, parking in ppoll(2) until raw has events or ctx is
+// done in between. Returns ctx.Err() once ctx is done.
+//
+// try must not block: on a descriptor without O_NONBLOCK it should read
+// with readNow or check readiness with ready first.
+func waitContext(ctx context.Context, raw int32, events int16, try func() error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := try(); err != iox.ErrWouldBlock {
+		return err
+	}
+	cfd, err := newContextFD(ctx)
+	if err != nil {
+		return err
+	}
+	defer cfd.close()
+
+	fds := [2]PollEntry{
+		{Fd: raw, Events: events},
+		{Fd: cfd.raw(), Events: POLLIN},
+	}
+	for {
+		if _, err := pollWait(fds[:]); err != nil {
+			return err
+		}
+		if fds[1].Revents != 0 {
+			return ctx.Err()
+		}
+		if fds[0].Revents&POLLNVAL != 0 {
+			return ErrClosed
+		}
+		if err := try(); err != iox.ErrWouldBlock {
+			return err
+		}
+	}
+}
+
+// contextFD makes the cancellation of a context pollable: its eventfd
+// becomes readable once the context is done.
+type contextFD struct {
+	efd  *EventFD
+	stop func() bool
+	done chan struct{}
+}
+
+// newContextFD returns a contextFD for ctx, or nil if ctx is never done.
+func newContextFD(ctx context.Context) (*contextFD, error) {
+	if ctx.Done() == nil {
+		return nil, nil
+	}
+	efd, err := NewEventFD(0)
+	if err != nil {
+		return nil, err
+	}
+	c := &contextFD{efd: efd, done: make(chan struct{})}
+	c.stop = context.AfterFunc(ctx, func() {
+		_ = efd.Signal(1)
+		close(c.done)
+	})
+	return c, nil
+}
+
+// raw returns the descriptor to poll, or -1 if c is nil.
+func (c *contextFD) raw() int32 {
+	if c == nil {
+		return -1
+	}
+	return c.efd.fd.Raw()
+}
+
+// close stops watching the context and closes the eventfd once no
+// cancellation callback can still write to it.
+func (c *contextFD) close() {
+	if c == nil {
+		return
+	}
+	if !c.stop() {
+		<-c.done
+	}
+	_ = c.efd.Close()
+}
+
+// preadv2 flags
+const (
+	RWF_NOWAIT = 0x8
+)
+
+// poll event flags
+const (
+	POLLIN   = 0x1
+	POLLPRI  = 0x2
+	POLLOUT  = 0x4
+	POLLERR  = 0x8
+	POLLHUP  = 0x10
+	POLLNVAL = 0x20
+)
@@ -0,0 +1,189 @@
+// ©Hayabusa Cloud Co., Ltd. 2025. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+//go:build linux
+
+package iofd
+
+import (
+	"sync/atomic"
+	"unsafe"
+
+	"code.hybscloud.com/zcall"
+)
+
+// PosixTimer represents a kernel POSIX per-process timer created with
+// timer_create(2) that notifies expirations by sending a signal to a
+// specific thread (SIGEV_THREAD_ID).
+//
+// Unlike TimerFD, a POSIX timer accepts CPU-time clocks such as
+// CLOCK_PROCESS_CPUTIME_ID and CLOCK_THREAD_CPUTIME_ID, and reports
+// expirations that occur while its signal is pending as an overrun count.
+//
+// Expirations are accepted through a SignalFD on the target thread, which
+// must block the signal; NewSignalFDThread sets up both. A real-time signal
+// is recommended, and Expirations decodes the SignalInfo.
+//
+// A PosixTimer is not a file descriptor and cannot be polled directly.
+type PosixTimer struct {
+	id     int32 // Kernel timer ID, -1 once deleted
+	signal int
+	tid    int
+}
+
+// NewPosixTimer creates a disarmed POSIX timer on clockid that sends sig to
+// the thread tid of the calling process on expiration. A tid of 0 targets
+// the calling thread, which must then stay locked with runtime.LockOSThread.
+func NewPosixTimer(clockid, sig, tid int) (*PosixTimer, error) {
+	if sig < 1 || sig > 64 || tid < 0 {
+		return nil, ErrInvalidParam
+	}
+	if tid == 0 {
+		tid = gettid()
+	}
+	ev := sigevent{
+		signo:  int32(sig),
+		notify: SIGEV_THREAD_ID,
+		tid:    int32(tid),
+	}
+	var id int32
+	_, errno := zcall.Syscall4(
+		SYS_TIMER_CREATE,
+		uintptr(clockid),
+		uintptr(unsafe.Pointer(&ev)),
+		uintptr(unsafe.Pointer(&id)),
+		0,
+	)
+	if errno != 0 {
+		return nil, errFromErrno(errno)
+	}
+	return &PosixTimer{id: id, signal: sig, tid: tid}, nil
+}
+
+// ID returns the kernel timer ID, reported as SignalInfo.TID on expiration.
+// Returns -1 after Close.
+func (t *PosixTimer) ID() int {
+	return int(atomic.LoadInt32(&t.id))
+}
+
+// Signal returns the signal sent on expiration.
+func (t *PosixTimer) Signal() int {
+	return t.signal
+}
+
+// TID returns the thread the expiration signal is sent to.
+func (t *PosixTimer) TID() int {
+	return t.tid
+}
+
+// Close deletes the timer. A pending expiration signal is discarded.
+// It is safe to call Close multiple times.
+func (t *PosixTimer) Close() error {
+	id := atomic.SwapInt32(&t.id, -1)
+	if id < 0 {
+		return nil
+	}
+	_, errno := zcall.Syscall4(SYS_TIMER_DELETE, uintptr(id), 0, 0, 0)
+	if errno != 0 {
+		return errFromErrno(errno)
+	}
+	return nil
+}
+
+// Arm sets the timer to expire after initial nanoseconds, then every
+// interval nanoseconds if interval is non-zero. An initial of 0 disarms.
+func (t *PosixTimer) Arm(initial, interval int64) error {
+	return t.settime(0, initial, interval)
+}
+
+// ArmAt sets the timer to expire at the absolute time deadline of its
+// clock in nanoseconds, then every interval nanoseconds if non-zero.
+func (t *PosixTimer) ArmAt(deadline, interval int64) error {
+	return t.settime(TIMER_ABSTIME, deadline, interval)
+}
+
+// Disarm stops the timer.
+func (t *PosixTimer) Disarm() error {
+	return t.settime(0, 0, 0)
+}
+
+func (t *PosixTimer) settime(flags uintptr, value, interval int64) error {
+	id := atomic.LoadInt32(&t.id)
+	if id < 0 {
+		return ErrClosed
+	}
+	if value < 0 || interval < 0 {
+		return ErrInvalidParam
+	}
+	spec := itimerspec{interval: nsToTimespec(interval), value: nsToTimespec(value)}
+	_, errno := zcall.Syscall4(SYS_TIMER_SETTIME, uintptr(id), flags, uintptr(unsafe.Pointer(&spec)), 0)
+	if errno != 0 {
+		return errFromErrno(errno)
+	}
+	return nil
+}
+
+// GetTime returns the time until the next expiration and the interval in
+// nanoseconds. remaining is 0 if the timer is disarmed.
+func (t *PosixTimer) GetTime() (remaining, interval int64, err error) {
+	id := atomic.LoadInt32(&t.id)
+	if id < 0 {
+		return 0, 0, ErrClosed
+	}
+	var curr itimerspec
+	_, errno := zcall.Syscall4(SYS_TIMER_GETTIME, uintptr(id), uintptr(unsafe.Pointer(&curr)), 0, 0)
+	if errno != 0 {
+		return 0, 0, errFromErrno(errno)
+	}
+	remaining = curr.value.sec*1e9 + curr.value.nsec
+	interval = curr.interval.sec*1e9 + curr.interval.nsec
+	return remaining, interval, nil
+}
+
+// Overrun returns the overrun count of the last expiration signal
+// accepted: the number of additional expirations that occurred while the
+// signal was pending.
+func (t *PosixTimer) Overrun() (int, error) {
+	id := atomic.LoadInt32(&t.id)
+	if id < 0 {
+		return 0, ErrClosed
+	}
+	n, errno := zcall.Syscall4(SYS_TIMER_GETOVERRUN, uintptr(id), 0, 0, 0)
+	if errno != 0 {
+		return 0, errFromErrno(errno)
+	}
+	return int(n), nil
+}
+
+// Expirations decodes an expiration signal of this timer read from a
+// SignalFD and returns the number of expirations it stands for: one plus
+// the overrun count. The second result is false if info was not sent by
+// this timer.
+func (t *PosixTimer) Expirations(info *SignalInfo) (uint64, bool) {
+	if info.Code != SI_TIMER || int(info.Signo) != t.signal || int(info.TID) != t.ID() {
+		return 0, false
+	}
+	return 1 + uint64(info.Overrun), true
+}
+
+// sigevent matches struct sigevent for SIGEV_THREAD_ID notification.
+type sigevent struct {
+	value  uint64 // sigev_value
+	signo  int32
+	notify int32
+	tid    int32
+	_      [44]byte // Padding to 64 bytes
+}
+
+// sigevent notification methods
+const (
+	SIGEV_SIGNAL    = 0
+	SIGEV_NONE      = 1
+	SIGEV_THREAD_ID = 4
+)
+
+// timer_settime flags
+const (
+	TIMER_ABSTIME = 0x1
+)
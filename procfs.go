@@ -0,0 +1,133 @@
+// ©Hayabusa Cloud Co., Ltd. 2025. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+//go:build linux
+
+package iofd
+
+import (
+	"encoding/binary"
+	"unsafe"
+
+	"code.hybscloud.com/zcall"
+)
+
+// cString returns s as a null-terminated byte slice.
+func cString(s string) []byte {
+	b := make([]byte, len(s)+1)
+	copy(b, s)
+	return b
+}
+
+// openPath opens path relative to the current working directory.
+// O_CLOEXEC is always added to flags.
+func openPath(path string, flags uintptr) (FD, error) {
+	// The path must be null-terminated for the syscall
+	pathBytes := cString(path)
+
+	fd, errno := zcall.Syscall4(
+		SYS_OPENAT,
+		atFDCWD,
+		uintptr(unsafe.Pointer(&pathBytes[0])),
+		flags|O_CLOEXEC,
+		0,
+	)
+	if errno != 0 {
+		return InvalidFD, errFromErrno(errno)
+	}
+	return FD(fd), nil
+}
+
+// readFile reads the whole content of a small file such as a procfs entry.
+func readFile(path string) ([]byte, error) {
+	fd, err := openPath(path, O_RDONLY)
+	if err != nil {
+		return nil, err
+	}
+	defer fd.Close()
+
+	buf := make([]byte, 0, 512)
+	for {
+		if len(buf) == cap(buf) {
+			buf = append(buf, 0)[:len(buf)]
+		}
+		n, err := fd.Read(buf[len(buf):cap(buf)])
+		if err != nil {
+			return nil, err
+		}
+		if n == 0 {
+			return buf, nil
+		}
+		buf = buf[:len(buf)+n]
+	}
+}
+
+// readLink returns the target of the symbolic link at path.
+func readLink(path string) (string, error) {
+	pathBytes := cString(path)
+	for size := 128; ; size *= 2 {
+		buf := make([]byte, size)
+		n, errno := zcall.Syscall4(
+			SYS_READLINKAT,
+			atFDCWD,
+			uintptr(unsafe.Pointer(&pathBytes[0])),
+			uintptr(unsafe.Pointer(&buf[0])),
+			uintptr(len(buf)),
+		)
+		if errno != 0 {
+			return "", errFromErrno(errno)
+		}
+		// A full buffer may indicate truncation
+		if int(n) < size {
+			return string(buf[:n]), nil
+		}
+	}
+}
+
+// readDirNames returns the names of the entries in the directory at path,
+// excluding "." and "..".
+func readDirNames(path string) ([]string, error) {
+	fd, err := openPath(path, O_RDONLY)
+	if err != nil {
+		return nil, err
+	}
+	defer fd.Close()
+
+	var names []string
+	buf := make([]byte, 4096)
+	for {
+		n, errno := zcall.Syscall4(
+			SYS_GETDENTS64,
+			uintptr(fd.Raw()),
+			uintptr(unsafe.Pointer(&buf[0])),
+			uintptr(len(buf)),
+			0,
+		)
+		if errno != 0 {
+			return nil, errFromErrno(errno)
+		}
+		if n == 0 {
+			return names, nil
+		}
+		// Parse struct linux_dirent64 records:
+		// d_ino (8), d_off (8), d_reclen (2), d_type (1), d_name (null-terminated)
+		for off := 0; off < int(n); {
+			reclen := int(binary.NativeEndian.Uint16(buf[off+16:]))
+			name := buf[off+19 : off+reclen]
+			for i, c := range name {
+				if c == 0 {
+					name = name[:i]
+					break
+				}
+			}
+			if s := string(name); s != "." && s != ".." {
+				names = append(names, s)
+			}
+			off += reclen
+		}
+	}
+}
+
+// atFDCWD is AT_FDCWD (-100) as passed to the *at syscalls.
+const atFDCWD = ^uintptr(99)
@@ -0,0 +1,265 @@
+// ©Hayabusa Cloud Co., Ltd. 2025. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+//go:build linux
+
+package iofd
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"unsafe"
+
+	"code.hybscloud.com/iox"
+	"code.hybscloud.com/zcall"
+)
+
+// ReaperEventKind identifies the kind of a ReaperEvent.
+type ReaperEventKind uint8
+
+// Reaper event kinds.
+const (
+	// ReaperAdopted reports a new child: either started by this process
+	// or an orphaned descendant reparented to it. A pidfd is now held for it.
+	ReaperAdopted ReaperEventKind = iota + 1
+	// ReaperExited reports that a tracked child exited and has been reaped.
+	ReaperExited
+)
+
+// ReaperEvent describes a change in the set of children tracked by a Reaper.
+type ReaperEvent struct {
+	Kind   ReaperEventKind
+	PID    int
+	Status ExitStatus // Valid for ReaperExited
+}
+
+// Reaper tracks and reaps all children of the calling process, including
+// orphaned descendants reparented to it as a child subreaper.
+//
+// NewReaper marks the process with PR_SET_CHILD_SUBREAPER, so that orphans
+// anywhere below it are reparented to it instead of init. Every child is
+// tracked through a pidfd and reaped with waitid(P_PIDFD); a SignalFD for
+// SIGCHLD is also watched to pick up children not yet known.
+//
+// Fd returns an epoll descriptor that becomes readable when a tracked child
+// exits or SIGCHLD is accepted by the signalfd; call Reap to collect events.
+// The Go runtime consumes SIGCHLD unless it is blocked on the thread that
+// receives it, so after starting a child, call Reap once to start tracking
+// it instead of relying on SIGCHLD.
+//
+// A Reaper takes ownership of every child of the process: children must not
+// also be waited for elsewhere, e.g. with os/exec's Cmd.Wait.
+type Reaper struct {
+	mu       sync.Mutex
+	ep       FD
+	sfd      *SignalFD
+	children map[int]*PidFD
+	pending  []ReaperEvent
+	restore  bool // clear PR_SET_CHILD_SUBREAPER on Close
+}
+
+// NewReaper marks the calling process as a child subreaper and creates a
+// Reaper tracking its current children.
+func NewReaper() (*Reaper, error) {
+	var was int32
+	_, errno := zcall.Syscall4(SYS_PRCTL, PR_GET_CHILD_SUBREAPER, uintptr(unsafe.Pointer(&was)), 0, 0)
+	if errno != 0 {
+		return nil, errFromErrno(errno)
+	}
+	if was == 0 {
+		_, errno = zcall.Syscall4(SYS_PRCTL, PR_SET_CHILD_SUBREAPER, 1, 0, 0)
+		if errno != 0 {
+			return nil, errFromErrno(errno)
+		}
+	}
+
+	r := &Reaper{ep: InvalidFD, children: make(map[int]*PidFD), restore: was == 0}
+	var err error
+	if r.ep, err = epollCreate(); err != nil {
+		r.Close()
+		return nil, err
+	}
+	if r.sfd, err = NewSignalFD(SigSetOf(SIGCHLD)); err != nil {
+		r.Close()
+		return nil, err
+	}
+	if err = epollCtl(r.ep.Raw(), EPOLL_CTL_ADD, r.sfd.fd.Raw(), EPOLLIN, 0); err != nil {
+		r.Close()
+		return nil, err
+	}
+	if err = r.scan(); err != nil {
+		r.Close()
+		return nil, err
+	}
+	return r, nil
+}
+
+// Fd returns the epoll descriptor signalling pending events.
+// Implements PollFd interface.
+func (r *Reaper) Fd() int {
+	return r.ep.Fd()
+}
+
+// Close releases all pidfds and descriptors held by the Reaper and clears
+// PR_SET_CHILD_SUBREAPER if NewReaper set it. Children are not reaped.
+// Implements PollCloser interface.
+func (r *Reaper) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for pid, pfd := range r.children {
+		_ = pfd.Close()
+		delete(r.children, pid)
+	}
+	if r.sfd != nil {
+		_ = r.sfd.Close()
+	}
+	err := r.ep.Close()
+	if r.restore {
+		r.restore = false
+		_, _ = zcall.Syscall4(SYS_PRCTL, PR_SET_CHILD_SUBREAPER, 0, 0, 0)
+	}
+	return err
+}
+
+// Reap collects pending events into events and returns the number stored.
+// Exited children are reaped, and new children, including orphans
+// reparented after a tracked child exited, are adopted. Events that do not
+// fit into events are kept for the next call.
+//
+// Returns iox.ErrWouldBlock if no event is pending.
+func (r *Reaper) Reap(events []ReaperEvent) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.ep.Raw() < 0 {
+		return 0, ErrClosed
+	}
+
+	// Drain SIGCHLD notifications; the children are inspected below
+	var infos [4]SignalInfo
+	for {
+		if _, err := r.sfd.ReadBatch(infos[:]); err != nil {
+			break
+		}
+	}
+
+	for {
+		if err := r.scan(); err != nil {
+			return 0, err
+		}
+		exited, err := r.collect()
+		if err != nil {
+			return 0, err
+		}
+		// Children of an exited child are reparented before it becomes
+		// waitable, so rescan until no more children exit
+		if !exited {
+			break
+		}
+	}
+
+	n := copy(events, r.pending)
+	r.pending = r.pending[:copy(r.pending, r.pending[n:])]
+	if n == 0 && len(events) > 0 {
+		return 0, iox.ErrWouldBlock
+	}
+	return n, nil
+}
+
+// Children returns the PIDs of the currently tracked children in
+// ascending order.
+func (r *Reaper) Children() []int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	pids := make([]int, 0, len(r.children))
+	for pid := range r.children {
+		pids = append(pids, pid)
+	}
+	slices.Sort(pids)
+	return pids
+}
+
+// scan adopts children listed in /proc/self/task/[tid]/children that are
+// not yet tracked. A child reparented to a subreaper may appear in the
+// list of any thread of the process.
+func (r *Reaper) scan() error {
+	tids, err := readDirNames("/proc/self/task")
+	if err != nil {
+		return err
+	}
+	var found []int
+	for _, tid := range tids {
+		b, err := readFile("/proc/self/task/" + tid + "/children")
+		if err != nil {
+			// The thread may have exited since the listing
+			continue
+		}
+		for _, f := range strings.Fields(string(b)) {
+			pid, err := strconv.Atoi(f)
+			if err != nil || r.children[pid] != nil {
+				continue
+			}
+			found = append(found, pid)
+		}
+	}
+	slices.Sort(found)
+	for _, pid := range found {
+		pfd, err := NewPidFD(pid)
+		if err != nil {
+			// The child has already been reaped
+			continue
+		}
+		if err := epollCtl(r.ep.Raw(), EPOLL_CTL_ADD, pfd.fd.Raw(), EPOLLIN, uint64(pid)); err != nil {
+			_ = pfd.Close()
+			return err
+		}
+		r.children[pid] = pfd
+		r.pending = append(r.pending, ReaperEvent{Kind: ReaperAdopted, PID: pid})
+	}
+	return nil
+}
+
+// collect reaps tracked children that have exited, in ascending PID order.
+// Reports whether any child was reaped.
+func (r *Reaper) collect() (bool, error) {
+	pids := make([]int, 0, len(r.children))
+	for pid := range r.children {
+		pids = append(pids, pid)
+	}
+	slices.Sort(pids)
+
+	reaped := false
+	for _, pid := range pids {
+		pfd := r.children[pid]
+		status, err := pfd.wait(WEXITED | WNOHANG)
+		if err == iox.ErrWouldBlock {
+			continue
+		}
+		if err != nil && err != zcall.ECHILD {
+			return reaped, err
+		}
+		// ECHILD: the child was reaped elsewhere; stop tracking it
+		_ = epollCtl(r.ep.Raw(), EPOLL_CTL_DEL, pfd.fd.Raw(), 0, 0)
+		_ = pfd.Close()
+		delete(r.children, pid)
+		reaped = true
+		if err == nil {
+			r.pending = append(r.pending, ReaperEvent{Kind: ReaperExited, PID: pid, Status: status})
+		}
+	}
+	return reaped, nil
+}
+
+// prctl options
+const (
+	PR_SET_CHILD_SUBREAPER = 36
+	PR_GET_CHILD_SUBREAPER = 37
+)
+
+// Compile-time interface assertions
+var (
+	_ PollFd     = (*Reaper)(nil)
+	_ PollCloser = (*Reaper)(nil)
+)
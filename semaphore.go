@@ -0,0 +1,249 @@
+// ©Hayabusa Cloud Co., Ltd. 2025. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+//go:build linux
+
+package iofd
+
+import (
+	"context"
+	"strconv"
+	"sync/atomic"
+
+	"code.hybscloud.com/iox"
+)
+
+// Semaphore is a counting semaphore on an eventfd in semaphore mode.
+//
+// The count lives in the kernel, so a Semaphore can be shared with other
+// processes by passing its descriptor, through inheritance, SCM_RIGHTS or
+// pidfd_getfd(2), and adopting it there with NewSemaphoreFd or
+// NewSemaphoreEventFD. Fd becomes readable while units are available.
+//
+// Fairness: waiters are not queued. Every waiter polling the descriptor is
+// woken when units are released, and whichever reads first takes them, so
+// a waiter may starve under contention. Acquire of several units takes
+// them one at a time and holds those it has while waiting for the rest:
+// concurrent acquirers whose requests together exceed the count can block
+// each other until their contexts are done. Units held by a process are
+// not returned when it dies.
+type Semaphore struct {
+	efd *EventFD
+}
+
+// NewSemaphore creates a Semaphore with n available units.
+func NewSemaphore(n uint) (*Semaphore, error) {
+	efd, err := NewEventFDSemaphore(n)
+	if err != nil {
+		return nil, err
+	}
+	return &Semaphore{efd: efd}, nil
+}
+
+// NewSemaphoreEventFD creates a Semaphore on an existing eventfd, such as
+// one returned by PidFD.GetHandle, and takes ownership of it on success.
+// An eventfd without O_NONBLOCK keeps its file status flags, which other
+// descriptors and processes share; units are still taken without blocking.
+//
+// Returns ErrInvalidParam if the eventfd is not in semaphore mode. The mode
+// is read from /proc/self/fdinfo, which reports it only since Linux 6.5:
+// on older kernels the check is skipped, and adopting a counter eventfd
+// makes each unit taken drain the whole count.
+func NewSemaphoreEventFD(e *EventFD) (*Semaphore, error) {
+	raw := e.fd.Raw()
+	if raw < 0 {
+		return nil, ErrClosed
+	}
+	b, err := readFile("/proc/self/fdinfo/" + strconv.Itoa(int(raw)))
+	if err != nil {
+		return nil, err
+	}
+	// Kernels before 6.5 do not report the mode
+	if v, ok := parseFDInfo(string(b)).Field("eventfd-semaphore"); ok && v != "1" {
+		return nil, ErrInvalidParam
+	}
+	return &Semaphore{efd: e}, nil
+}
+
+// NewSemaphoreFd creates a Semaphore on an inherited eventfd descriptor and
+// takes ownership of it on success. Returns ErrInvalidParam if fd is not
+// an eventfd in semaphore mode.
+func NewSemaphoreFd(fd int) (*Semaphore, error) {
+	if fd < 0 {
+		return nil, ErrInvalidParam
+	}
+	h, err := newHandle(NewFD(fd))
+	if err != nil {
+		return nil, err
+	}
+	efd, ok := h.(*EventFD)
+	if !ok {
+		return nil, ErrInvalidParam
+	}
+	return NewSemaphoreEventFD(efd)
+}
+
+// Fd returns the underlying eventfd.
+// Implements PollFd interface.
+func (s *Semaphore) Fd() int {
+	return s.efd.Fd()
+}
+
+// Close closes the eventfd. Other processes sharing it are not affected.
+// Implements PollCloser interface.
+func (s *Semaphore) Close() error {
+	return s.efd.Close()
+}
+
+// Acquire takes n units, waiting until they are available or ctx is done.
+// On failure, including cancellation, units already taken are released
+// and ctx.Err() or the error is returned.
+func (s *Semaphore) Acquire(ctx context.Context, n uint64) error {
+	raw := s.efd.fd.Raw()
+	if raw < 0 {
+		return ErrClosed
+	}
+	for got := uint64(0); got < n; got++ {
+		if err := waitContext(ctx, raw, POLLIN, s.take); err != nil {
+			s.putBack(got)
+			return err
+		}
+	}
+	return nil
+}
+
+// TryAcquire takes n units if they are all available without waiting, and
+// reports whether it did. Other processes may briefly observe fewer units
+// while a failed attempt puts back the units it took.
+func (s *Semaphore) TryAcquire(n uint64) (bool, error) {
+	if s.efd.fd.Raw() < 0 {
+		return false, ErrClosed
+	}
+	for got := uint64(0); got < n; got++ {
+		if err := s.take(); err != nil {
+			s.putBack(got)
+			if err == iox.ErrWouldBlock {
+				return false, nil
+			}
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// Release returns n units, waking waiters in any process.
+// Returns ErrOverflow if the count would exceed 2^64 - 2.
+func (s *Semaphore) Release(n uint64) error {
+	err := s.efd.Signal(n)
+	if err == iox.ErrWouldBlock {
+		return ErrOverflow
+	}
+	return err
+}
+
+// take takes one unit without waiting, even on an eventfd without
+// O_NONBLOCK.
+func (s *Semaphore) take() error {
+	_, err := s.efd.tryWait(s.efd.fd.Raw())
+	return err
+}
+
+// putBack returns units taken by a failed acquisition.
+func (s *Semaphore) putBack(n uint64) {
+	if n > 0 {
+		_ = s.efd.Signal(n)
+	}
+}
+
+// Mutex is a mutual exclusion lock on a Semaphore with a single unit,
+// usable across processes like Semaphore and with the same fairness.
+//
+// Unlike sync.Mutex, the lock is not released when its holder dies, and
+// a Mutex value only unlocks what it has locked itself: each process
+// adopts the shared descriptor into its own Mutex.
+type Mutex struct {
+	s      *Semaphore
+	locked atomic.Bool
+}
+
+// NewMutex creates an unlocked Mutex.
+func NewMutex() (*Mutex, error) {
+	s, err := NewSemaphore(1)
+	if err != nil {
+		return nil, err
+	}
+	return &Mutex{s: s}, nil
+}
+
+// NewMutexEventFD creates a Mutex on the eventfd of another Mutex, such as
+// one returned by PidFD.GetHandle, and takes ownership of it on success.
+// See NewSemaphoreEventFD.
+func NewMutexEventFD(e *EventFD) (*Mutex, error) {
+	s, err := NewSemaphoreEventFD(e)
+	if err != nil {
+		return nil, err
+	}
+	return &Mutex{s: s}, nil
+}
+
+// NewMutexFd creates a Mutex on an inherited eventfd descriptor of another
+// Mutex and takes ownership of it on success. See NewSemaphoreFd.
+func NewMutexFd(fd int) (*Mutex, error) {
+	s, err := NewSemaphoreFd(fd)
+	if err != nil {
+		return nil, err
+	}
+	return &Mutex{s: s}, nil
+}
+
+// Fd returns the underlying eventfd, readable while the mutex is unlocked.
+// Implements PollFd interface.
+func (m *Mutex) Fd() int {
+	return m.s.Fd()
+}
+
+// Close closes the eventfd. A lock held through m stays held.
+// Implements PollCloser interface.
+func (m *Mutex) Close() error {
+	return m.s.Close()
+}
+
+// Lock locks m, waiting until it is unlocked or ctx is done.
+func (m *Mutex) Lock(ctx context.Context) error {
+	if err := m.s.Acquire(ctx, 1); err != nil {
+		return err
+	}
+	m.locked.Store(true)
+	return nil
+}
+
+// TryLock locks m if it is unlocked and reports whether it did.
+func (m *Mutex) TryLock() (bool, error) {
+	ok, err := m.s.TryAcquire(1)
+	if ok {
+		m.locked.Store(true)
+	}
+	return ok, err
+}
+
+// Unlock unlocks m. Returns ErrInvalidParam if m has not been locked
+// through this Mutex value.
+func (m *Mutex) Unlock() error {
+	if !m.locked.CompareAndSwap(true, false) {
+		return ErrInvalidParam
+	}
+	if err := m.s.Release(1); err != nil {
+		m.locked.Store(true)
+		return err
+	}
+	return nil
+}
+
+// Compile-time interface assertions
+var (
+	_ PollFd     = (*Semaphore)(nil)
+	_ PollCloser = (*Semaphore)(nil)
+	_ PollFd     = (*Mutex)(nil)
+	_ PollCloser = (*Mutex)(nil)
+)
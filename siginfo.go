@@ -0,0 +1,277 @@
+// ©Hayabusa Cloud Co., Ltd. 2025. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+//go:build linux
+
+package iofd
+
+import "strconv"
+
+// SignalSource describes how a signal was generated, as decoded from si_code.
+type SignalSource int32
+
+// Signal sources.
+const (
+	SourceUser    SignalSource = SI_USER    // kill(2), pidfd_send_signal(2) or raise(3)
+	SourceKernel  SignalSource = SI_KERNEL  // Generated by the kernel
+	SourceQueue   SignalSource = SI_QUEUE   // sigqueue(3)
+	SourceTimer   SignalSource = SI_TIMER   // POSIX timer expiration
+	SourceMesgQ   SignalSource = SI_MESGQ   // POSIX message queue state change
+	SourceAsyncIO SignalSource = SI_ASYNCIO // AIO completion
+	SourceSigIO   SignalSource = SI_SIGIO   // Queued SIGIO
+	SourceTKill   SignalSource = SI_TKILL   // tkill(2) or tgkill(2)
+)
+
+// String returns a short lowercase name for the source.
+func (s SignalSource) String() string {
+	switch s {
+	case SourceUser:
+		return "user"
+	case SourceKernel:
+		return "kernel"
+	case SourceQueue:
+		return "queue"
+	case SourceTimer:
+		return "timer"
+	case SourceMesgQ:
+		return "mesgq"
+	case SourceAsyncIO:
+		return "asyncio"
+	case SourceSigIO:
+		return "sigio"
+	case SourceTKill:
+		return "tkill"
+	default:
+		return "unknown"
+	}
+}
+
+// Source returns how the signal was generated.
+// Positive si_code values are specific to the signal, such as CLD_EXITED
+// or SEGV_MAPERR, and are always generated by the kernel, so they are
+// reported as SourceKernel.
+func (i *SignalInfo) Source() SignalSource {
+	if i.Code > 0 {
+		return SourceKernel
+	}
+	return SignalSource(i.Code)
+}
+
+// ChildEvent returns the child state change reported by a SIGCHLD sent by
+// the kernel. The second result is false for any other signal.
+func (i *SignalInfo) ChildEvent() (ExitStatus, bool) {
+	if i.Signo != SIGCHLD || i.Code < CLD_EXITED || i.Code > CLD_CONTINUED {
+		return ExitStatus{}, false
+	}
+	return ExitStatus{Code: i.Code, Status: i.Status}, true
+}
+
+// SignalFault describes a hardware fault or trap reported by SIGSEGV,
+// SIGBUS, SIGILL, SIGFPE or SIGTRAP.
+type SignalFault struct {
+	Signal  int    // Signal number
+	Code    int32  // Signal-specific code, e.g. SEGV_MAPERR or BUS_ADRALN
+	Addr    uint64 // Faulting memory address or instruction
+	AddrLsb uint16 // Least significant bit of the address for BUS_MCEERR_*
+	Trapno  uint32 // Trap number on architectures that report it
+}
+
+// String returns the fault code name and address, e.g.
+// "SEGV_MAPERR addr=0x0".
+func (f SignalFault) String() string {
+	return faultCodeName(f.Signal, f.Code) + " addr=0x" + strconv.FormatUint(f.Addr, 16)
+}
+
+// Fault returns the fault details of a signal generated by the kernel for
+// a hardware fault or trap. The second result is false for any other signal.
+func (i *SignalInfo) Fault() (SignalFault, bool) {
+	switch i.Signo {
+	case SIGSEGV, SIGBUS, SIGILL, SIGFPE, SIGTRAP:
+	default:
+		return SignalFault{}, false
+	}
+	if i.Code <= 0 {
+		// Sent by a process, not raised by a fault
+		return SignalFault{}, false
+	}
+	return SignalFault{
+		Signal:  int(i.Signo),
+		Code:    i.Code,
+		Addr:    i.Addr,
+		AddrLsb: i.AddrLsb,
+		Trapno:  i.Trapno,
+	}, true
+}
+
+// String renders the signal on a single line for logging, e.g.
+// "SIGTERM from pid 42 uid 1000 (user)" or
+// "SIGCHLD pid 42: exit status 1".
+func (i *SignalInfo) String() string {
+	name := SignalName(int(i.Signo))
+	if st, ok := i.ChildEvent(); ok {
+		return name + " pid " + strconv.FormatUint(uint64(i.PID), 10) + ": " + st.String()
+	}
+	if f, ok := i.Fault(); ok {
+		return name + " " + f.String()
+	}
+	src := i.Source()
+	switch src {
+	case SourceTimer:
+		return name + " timer " + strconv.FormatUint(uint64(i.TID), 10) +
+			" overrun " + strconv.FormatUint(uint64(i.Overrun), 10)
+	case SourceKernel:
+		if i.Signo == SIGIO {
+			return name + " fd " + strconv.Itoa(int(i.FD)) + " band 0x" + strconv.FormatUint(uint64(i.Band), 16)
+		}
+		return name + " (kernel)"
+	}
+	s := name + " from pid " + strconv.FormatUint(uint64(i.PID), 10) +
+		" uid " + strconv.FormatUint(uint64(i.UID), 10) + " (" + src.String() + ")"
+	if src == SourceQueue {
+		s += " value " + strconv.Itoa(int(i.Int))
+	}
+	return s
+}
+
+// Names of signal-specific fault si_code values.
+var (
+	segvCodeNames = [...]string{
+		SEGV_MAPERR:  "SEGV_MAPERR",
+		SEGV_ACCERR:  "SEGV_ACCERR",
+		SEGV_BNDERR:  "SEGV_BNDERR",
+		SEGV_PKUERR:  "SEGV_PKUERR",
+		SEGV_ACCADI:  "SEGV_ACCADI",
+		SEGV_ADIDERR: "SEGV_ADIDERR",
+		SEGV_ADIPERR: "SEGV_ADIPERR",
+		SEGV_MTEAERR: "SEGV_MTEAERR",
+		SEGV_MTESERR: "SEGV_MTESERR",
+		SEGV_CPERR:   "SEGV_CPERR",
+	}
+	busCodeNames = [...]string{
+		BUS_ADRALN:    "BUS_ADRALN",
+		BUS_ADRERR:    "BUS_ADRERR",
+		BUS_OBJERR:    "BUS_OBJERR",
+		BUS_MCEERR_AR: "BUS_MCEERR_AR",
+		BUS_MCEERR_AO: "BUS_MCEERR_AO",
+	}
+	illCodeNames = [...]string{
+		ILL_ILLOPC: "ILL_ILLOPC",
+		ILL_ILLOPN: "ILL_ILLOPN",
+		ILL_ILLADR: "ILL_ILLADR",
+		ILL_ILLTRP: "ILL_ILLTRP",
+		ILL_PRVOPC: "ILL_PRVOPC",
+		ILL_PRVREG: "ILL_PRVREG",
+		ILL_COPROC: "ILL_COPROC",
+		ILL_BADSTK: "ILL_BADSTK",
+	}
+	fpeCodeNames = [...]string{
+		FPE_INTDIV: "FPE_INTDIV",
+		FPE_INTOVF: "FPE_INTOVF",
+		FPE_FLTDIV: "FPE_FLTDIV",
+		FPE_FLTOVF: "FPE_FLTOVF",
+		FPE_FLTUND: "FPE_FLTUND",
+		FPE_FLTRES: "FPE_FLTRES",
+		FPE_FLTINV: "FPE_FLTINV",
+		FPE_FLTSUB: "FPE_FLTSUB",
+	}
+	trapCodeNames = [...]string{
+		TRAP_BRKPT:  "TRAP_BRKPT",
+		TRAP_TRACE:  "TRAP_TRACE",
+		TRAP_BRANCH: "TRAP_BRANCH",
+		TRAP_HWBKPT: "TRAP_HWBKPT",
+		TRAP_UNK:    "TRAP_UNK",
+		TRAP_PERF:   "TRAP_PERF",
+	}
+)
+
+// faultCodeName returns the name of a fault si_code for the signal,
+// or "code N" if it is not known.
+func faultCodeName(sig int, code int32) string {
+	var names []string
+	switch sig {
+	case SIGSEGV:
+		names = segvCodeNames[:]
+	case SIGBUS:
+		names = busCodeNames[:]
+	case SIGILL:
+		names = illCodeNames[:]
+	case SIGFPE:
+		names = fpeCodeNames[:]
+	case SIGTRAP:
+		names = trapCodeNames[:]
+	}
+	if code > 0 && int(code) < len(names) {
+		return names[code]
+	}
+	return "code " + strconv.Itoa(int(code))
+}
+
+// si_code values for signals sent by processes and the kernel
+const (
+	SI_USER    = 0
+	SI_KERNEL  = 0x80
+	SI_QUEUE   = -1
+	SI_TIMER   = -2
+	SI_MESGQ   = -3
+	SI_ASYNCIO = -4
+	SI_SIGIO   = -5
+	SI_TKILL   = -6
+)
+
+// SIGSEGV si_code values
+const (
+	SEGV_MAPERR  = 1
+	SEGV_ACCERR  = 2
+	SEGV_BNDERR  = 3
+	SEGV_PKUERR  = 4
+	SEGV_ACCADI  = 5
+	SEGV_ADIDERR = 6
+	SEGV_ADIPERR = 7
+	SEGV_MTEAERR = 8
+	SEGV_MTESERR = 9
+	SEGV_CPERR   = 10
+)
+
+// SIGBUS si_code values
+const (
+	BUS_ADRALN    = 1
+	BUS_ADRERR    = 2
+	BUS_OBJERR    = 3
+	BUS_MCEERR_AR = 4
+	BUS_MCEERR_AO = 5
+)
+
+// SIGILL si_code values
+const (
+	ILL_ILLOPC = 1
+	ILL_ILLOPN = 2
+	ILL_ILLADR = 3
+	ILL_ILLTRP = 4
+	ILL_PRVOPC = 5
+	ILL_PRVREG = 6
+	ILL_COPROC = 7
+	ILL_BADSTK = 8
+)
+
+// SIGFPE si_code values
+const (
+	FPE_INTDIV = 1
+	FPE_INTOVF = 2
+	FPE_FLTDIV = 3
+	FPE_FLTOVF = 4
+	FPE_FLTUND = 5
+	FPE_FLTRES = 6
+	FPE_FLTINV = 7
+	FPE_FLTSUB = 8
+)
+
+// SIGTRAP si_code values
+const (
+	TRAP_BRKPT  = 1
+	TRAP_TRACE  = 2
+	TRAP_BRANCH = 3
+	TRAP_HWBKPT = 4
+	TRAP_UNK    = 5
+	TRAP_PERF   = 6
+)
@@ -0,0 +1,128 @@
+// ©Hayabusa Cloud Co., Ltd. 2025. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+//go:build linux
+
+package iofd
+
+import (
+	"runtime"
+	"unsafe"
+
+	"code.hybscloud.com/zcall"
+)
+
+// Signal masks are per thread in Linux, and the Go runtime moves goroutines
+// between OS threads freely. The functions below act on the calling OS
+// thread only, so callers must hold runtime.LockOSThread for the mask to
+// stay in effect for their goroutine. Threads created later by the runtime
+// start with the mask the process had at startup, not with the mask of the
+// thread that created them.
+
+// BlockSignals adds set to the signal mask of the calling OS thread.
+// Returns the previous mask.
+func BlockSignals(set SigSet) (SigSet, error) {
+	return sigprocmask(SIG_BLOCK, &set)
+}
+
+// UnblockSignals removes set from the signal mask of the calling OS thread.
+// Returns the previous mask.
+func UnblockSignals(set SigSet) (SigSet, error) {
+	return sigprocmask(SIG_UNBLOCK, &set)
+}
+
+// SetSignalMask replaces the signal mask of the calling OS thread with set.
+// Returns the previous mask.
+func SetSignalMask(set SigSet) (SigSet, error) {
+	return sigprocmask(SIG_SETMASK, &set)
+}
+
+// CurrentMask returns the signal mask of the calling OS thread.
+func CurrentMask() (SigSet, error) {
+	return sigprocmask(SIG_BLOCK, nil)
+}
+
+// sigprocmask calls rt_sigprocmask(2). A nil set only queries the mask.
+// SIGKILL and SIGSTOP are silently ignored by the kernel.
+func sigprocmask(how uintptr, set *SigSet) (SigSet, error) {
+	var old SigSet
+	_, errno := zcall.Syscall4(
+		SYS_RT_SIGPROCMASK,
+		how,
+		uintptr(unsafe.Pointer(set)),
+		uintptr(unsafe.Pointer(&old)),
+		unsafe.Sizeof(old),
+	)
+	if errno != 0 {
+		return 0, errFromErrno(errno)
+	}
+	return old, nil
+}
+
+// gettid returns the kernel thread ID of the calling OS thread.
+func gettid() int {
+	tid, _ := zcall.Syscall4(SYS_GETTID, 0, 0, 0, 0)
+	return int(tid)
+}
+
+// NewSignalFDThread locks the calling goroutine to its OS thread, blocks
+// the signals in mask on that thread and creates a signalfd for them.
+//
+// This is the thread-bound model for signalfd in Go: signals directed to
+// the locked thread, e.g. with tgkill(2) or by a POSIX timer using
+// SIGEV_THREAD_ID, are accepted only through the signalfd. Signals directed
+// to the process are still delivered to any other thread that does not
+// block them, usually to the Go runtime's handlers.
+//
+// The signalfd must be read and closed by the same goroutine. Close
+// restores the previous mask and unlocks the thread; called on another
+// thread, it closes the descriptor and returns ErrWrongThread, leaving the
+// original thread locked with the mask in place.
+func NewSignalFDThread(mask SigSet) (*SignalFD, error) {
+	runtime.LockOSThread()
+	saved, err := BlockSignals(mask)
+	if err != nil {
+		runtime.UnlockOSThread()
+		return nil, err
+	}
+	s, err := newSignalFD(mask, SFD_NONBLOCK|SFD_CLOEXEC)
+	if err != nil {
+		_, _ = SetSignalMask(saved)
+		runtime.UnlockOSThread()
+		return nil, err
+	}
+	s.tid = gettid()
+	s.saved = saved
+	return s, nil
+}
+
+// TID returns the kernel thread ID the signalfd is bound to, or 0 if it
+// was not created by NewSignalFDThread.
+func (s *SignalFD) TID() int {
+	return s.tid
+}
+
+// closeThread closes a thread-bound signalfd, restoring the thread's
+// signal mask when called on the owning thread.
+func (s *SignalFD) closeThread() error {
+	err := s.fd.Close()
+	if gettid() != s.tid {
+		s.tid = 0
+		return ErrWrongThread
+	}
+	s.tid = 0
+	if _, merr := SetSignalMask(s.saved); merr != nil {
+		// Stay locked so the runtime discards the thread on goroutine exit
+		return merr
+	}
+	runtime.UnlockOSThread()
+	return err
+}
+
+// rt_sigprocmask how values
+const (
+	SIG_BLOCK   = 0
+	SIG_UNBLOCK = 1
+	SIG_SETMASK = 2
+)
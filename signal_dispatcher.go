@@ -0,0 +1,192 @@
+// ©Hayabusa Cloud Co., Ltd. 2025. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+//go:build linux
+
+package iofd
+
+import (
+	"context"
+	"sync"
+
+	"code.hybscloud.com/iox"
+)
+
+// SignalHandler handles a burst of one signal accepted by a
+// SignalDispatcher. info describes the most recent instance and n is the
+// number of instances coalesced into this call.
+type SignalHandler func(info *SignalInfo, n int)
+
+// dispatchBatch is the number of signals read per read(2) call, and
+// dispatchMaxBatches bounds the reads of a single Dispatch so that a flood
+// of queued real-time signals cannot starve the caller.
+const (
+	dispatchBatch      = 16
+	dispatchMaxBatches = 8
+)
+
+// SignalDispatcher owns a SignalFD and calls a handler per signal.
+//
+// The signalfd mask always equals the set of signals with a registered
+// handler. As with NewSignalFD, the caller must block those signals on the
+// thread that dispatches, e.g. with BlockSignals on a locked thread, or
+// they are delivered by default instead.
+//
+// Fd returns the signalfd for use in an external event loop: call Dispatch
+// whenever it becomes readable. Alternatively, Run blocks and dispatches
+// until its context is done.
+type SignalDispatcher struct {
+	mu       sync.Mutex // guards handlers and the signalfd mask
+	handlers [65]SignalHandler
+
+	dmu   sync.Mutex // serializes Dispatch
+	sfd   *SignalFD
+	buf   [dispatchBatch]SignalInfo
+	burst [65]struct {
+		info SignalInfo
+		n    int
+	}
+}
+
+// NewSignalDispatcher creates a SignalDispatcher with no handlers.
+func NewSignalDispatcher() (*SignalDispatcher, error) {
+	sfd, err := NewSignalFD(0)
+	if err != nil {
+		return nil, err
+	}
+	return &SignalDispatcher{sfd: sfd}, nil
+}
+
+// Fd returns the underlying signalfd.
+// Implements PollFd interface.
+func (d *SignalDispatcher) Fd() int {
+	return d.sfd.Fd()
+}
+
+// Close closes the signalfd. Pending signals stay pending.
+// Implements PollCloser interface.
+func (d *SignalDispatcher) Close() error {
+	return d.sfd.Close()
+}
+
+// Handle registers h as the handler for sig, replacing any previous one,
+// and updates the signalfd mask. A nil h removes the handler; signals
+// already read for sig are then dropped.
+//
+// Handlers run on the goroutine calling Dispatch or Run and may call
+// Handle, but must not call Dispatch.
+func (d *SignalDispatcher) Handle(sig int, h SignalHandler) error {
+	if sig < 1 || sig > 64 || runtimeSignals.Has(sig) {
+		return ErrInvalidParam
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	mask := d.sfd.Mask()
+	if h != nil {
+		mask.Add(sig)
+	} else {
+		mask.Del(sig)
+	}
+	if mask != d.sfd.Mask() {
+		if err := d.sfd.SetMask(mask); err != nil {
+			return err
+		}
+	}
+	d.handlers[sig] = h
+	return nil
+}
+
+// Mask returns the signals that currently have a handler.
+func (d *SignalDispatcher) Mask() SigSet {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.sfd.Mask()
+}
+
+// Dispatch reads the pending signals and calls their handlers, once per
+// signal number in ascending order, coalescing repeated instances.
+// Returns the number of handlers called, or iox.ErrWouldBlock if no
+// signal was pending.
+func (d *SignalDispatcher) Dispatch() (int, error) {
+	d.dmu.Lock()
+	defer d.dmu.Unlock()
+
+	var pending SigSet
+	for range dispatchMaxBatches {
+		n, err := d.sfd.ReadBatch(d.buf[:])
+		if err == iox.ErrWouldBlock {
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+		for i := range d.buf[:n] {
+			sig := int(d.buf[i].Signo)
+			if sig < 1 || sig > 64 {
+				continue
+			}
+			if !pending.Has(sig) {
+				pending.Add(sig)
+				d.burst[sig].n = 0
+			}
+			d.burst[sig].info = d.buf[i]
+			d.burst[sig].n++
+		}
+		if n < len(d.buf) {
+			break
+		}
+	}
+	if pending.Empty() {
+		return 0, iox.ErrWouldBlock
+	}
+
+	called := 0
+	for sig := range pending.Signals() {
+		d.mu.Lock()
+		h := d.handlers[sig]
+		d.mu.Unlock()
+		if h != nil {
+			h(&d.burst[sig].info, d.burst[sig].n)
+			called++
+		}
+	}
+	return called, nil
+}
+
+// Run dispatches signals on the calling goroutine until ctx is done,
+// returning the context's error, or until a read fails. The goroutine
+// blocks in ppoll(2) while idle, releasing its P as Poll does; closing the
+// dispatcher does not wake it, so cancel ctx before calling Close.
+func (d *SignalDispatcher) Run(ctx context.Context) error {
+	cfd, err := newContextFD(ctx)
+	if err != nil {
+		return err
+	}
+	defer cfd.close()
+
+	fds := [2]PollEntry{
+		{Fd: d.sfd.fd.Raw(), Events: POLLIN},
+		{Fd: cfd.raw(), Events: POLLIN},
+	}
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if _, err := d.Dispatch(); err != nil && err != iox.ErrWouldBlock {
+			return err
+		}
+		if fds[0].Fd = d.sfd.fd.Raw(); fds[0].Fd < 0 {
+			return ErrClosed
+		}
+		if _, err := pollWait(fds[:]); err != nil {
+			return err
+		}
+	}
+}
+
+// Compile-time interface assertions
+var (
+	_ PollFd     = (*SignalDispatcher)(nil)
+	_ PollCloser = (*SignalDispatcher)(nil)
+)
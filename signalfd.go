@@ -7,9 +7,9 @@
 package iofd
 
 import (
+	"context"
 	"unsafe"
 
-	"code.hybscloud.com/iox"
 	"code.hybscloud.com/zcall"
 )
 
@@ -20,11 +20,18 @@ import (
 // SignalFD is created with SFD_NONBLOCK and SFD_CLOEXEC by default.
 //
 // Invariants:
-//   - The caller must block the signals with sigprocmask before using signalfd.
-//   - Each Read returns exactly one SignalInfo structure (128 bytes).
+//   - The caller must block the signals (see BlockSignals) before using signalfd.
+//   - Each Read returns exactly one SignalInfo structure (128 bytes);
+//     ReadBatch returns as many as fit into the caller's slice.
 type SignalFD struct {
-	fd   FD
-	mask SigSet
+	fd    FD
+	mask  SigSet
+	tid   int    // Owning thread for NewSignalFDThread, 0 otherwise
+	saved SigSet // Thread mask to restore on Close
+
+	forward *signalForwarder // os/signal coordination for NewSignalFDInterop
+
+	blocking bool // Adopted without O_NONBLOCK
 }
 
 // SigSet represents a signal set for signalfd operations.
@@ -128,8 +135,9 @@ const signalInfoSize = 128
 // NewSignalFD creates a new signalfd monitoring the given signal set.
 // The signalfd is created with SFD_NONBLOCK | SFD_CLOEXEC flags.
 //
-// The caller should block the signals in the set using sigprocmask
-// before creating the signalfd to prevent default signal handling.
+// The caller should block the signals in the set, e.g. with BlockSignals
+// on a locked thread, before creating the signalfd to prevent default
+// signal handling. NewSignalFDThread does both.
 func NewSignalFD(mask SigSet) (*SignalFD, error) {
 	return newSignalFD(mask, SFD_NONBLOCK|SFD_CLOEXEC)
 }
@@ -154,34 +162,114 @@ func (s *SignalFD) Fd() int {
 	return s.fd.Fd()
 }
 
-// Close closes the signalfd.
+// Close closes the signalfd. For a signalfd created by NewSignalFDThread,
+// Close also restores the thread's signal mask and unlocks the thread; for
+// one created by NewSignalFDInterop, it stops the os/signal registration.
 // Implements PollCloser interface.
 func (s *SignalFD) Close() error {
+	if s.tid != 0 {
+		return s.closeThread()
+	}
+	if f := s.forward; f != nil {
+		s.forward = nil
+		f.stop()
+	}
 	return s.fd.Close()
 }
 
-// Read reads signal information into the provided SignalInfo.
+// Read reads the next pending signal into a newly allocated SignalInfo.
 // Returns iox.ErrWouldBlock if no signal is pending.
 //
-// Postcondition: On success, info contains the next pending signal.
+// Postcondition: On success, the returned SignalInfo contains the next
+// pending signal.
 func (s *SignalFD) Read() (*SignalInfo, error) {
+	info := new(SignalInfo)
+	if err := s.ReadInfo(info); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// ReadInfo reads the next pending signal into the caller-owned dst.
+// It does not allocate. Returns iox.ErrWouldBlock if no signal is pending;
+// a signalfd adopted without O_NONBLOCK parks in ppoll(2) until one is.
+//
+// Postcondition: On success, dst contains the next pending signal.
+func (s *SignalFD) ReadInfo(dst *SignalInfo) error {
+	if dst == nil {
+		return ErrInvalidParam
+	}
+	raw := s.fd.Raw()
+	if raw < 0 {
+		return ErrClosed
+	}
+	buf := (*[signalInfoSize]byte)(unsafe.Pointer(dst))[:]
+	n, err := readWait(raw, buf, s.blocking, errFromErrno)
+	if err != nil {
+		return err
+	}
+	if n != signalInfoSize {
+		return ErrInvalidParam
+	}
+	return nil
+}
+
+// ReadContext is like Read, but parks the calling goroutine until a
+// signal is pending or ctx is done, returning ctx.Err() in the latter
+// case. The wait uses ppoll(2) on the signalfd and on an eventfd signaled
+// on cancellation. Like Poll, it releases the P of the calling goroutine
+// while parked.
+func (s *SignalFD) ReadContext(ctx context.Context) (*SignalInfo, error) {
 	raw := s.fd.Raw()
 	if raw < 0 {
 		return nil, ErrClosed
 	}
-	var info SignalInfo
-	buf := (*[signalInfoSize]byte)(unsafe.Pointer(&info))[:]
-	n, errno := zcall.Read(uintptr(raw), buf)
-	if errno != 0 {
-		if zcall.Errno(errno) == zcall.EAGAIN {
-			return nil, iox.ErrWouldBlock
+	info := new(SignalInfo)
+	buf := (*[signalInfoSize]byte)(unsafe.Pointer(info))[:]
+	err := waitContext(ctx, raw, POLLIN, func() error {
+		n, errno := readNow(raw, buf, s.blocking)
+		if errno != 0 {
+			return errFromErrno(errno)
 		}
-		return nil, errFromErrno(errno)
+		if n != signalInfoSize {
+			return ErrInvalidParam
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
-	if n != signalInfoSize {
-		return nil, ErrInvalidParam
+	return info, nil
+}
+
+// ReadBatch reads as many pending signals as fit into dst with a single
+// read(2) and returns the number stored. It does not allocate.
+// Returns iox.ErrWouldBlock if no signal is pending.
+func (s *SignalFD) ReadBatch(dst []SignalInfo) (int, error) {
+	raw := s.fd.Raw()
+	if raw < 0 {
+		return 0, ErrClosed
+	}
+	if len(dst) == 0 {
+		return 0, nil
+	}
+	buf := unsafe.Slice((*byte)(unsafe.Pointer(&dst[0])), len(dst)*signalInfoSize)
+	n, err := readWait(raw, buf, s.blocking, errFromErrno)
+	if err != nil {
+		return 0, err
 	}
-	return &info, nil
+	return n / signalInfoSize, nil
+}
+
+// tryReadBatch is ReadBatch without waiting, also in blocking mode.
+// dst must not be empty.
+func (s *SignalFD) tryReadBatch(raw int32, dst []SignalInfo) (int, error) {
+	buf := unsafe.Slice((*byte)(unsafe.Pointer(&dst[0])), len(dst)*signalInfoSize)
+	n, errno := readNow(raw, buf, s.blocking)
+	if errno != 0 {
+		return 0, errFromErrno(errno)
+	}
+	return int(n) / signalInfoSize, nil
 }
 
 // ReadInto reads signal information into the provided buffer.
@@ -194,11 +282,7 @@ func (s *SignalFD) ReadInto(buf []byte) (int, error) {
 	if raw < 0 {
 		return 0, ErrClosed
 	}
-	n, errno := zcall.Read(uintptr(raw), buf[:signalInfoSize])
-	if errno != 0 {
-		return int(n), errFromErrno(errno)
-	}
-	return int(n), nil
+	return readWait(raw, buf[:signalInfoSize], s.blocking, errFromErrno)
 }
 
 // SetMask updates the signal mask monitored by this signalfd.
@@ -0,0 +1,144 @@
+// ©Hayabusa Cloud Co., Ltd. 2025. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+//go:build linux
+
+package iofd
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+	"unsafe"
+
+	"code.hybscloud.com/zcall"
+)
+
+// runtimeSignals are signals a signalfd must not take: SIGKILL and SIGSTOP
+// cannot be caught, SIGURG drives goroutine preemption, and 32 and 33 are
+// reserved by the Go runtime and the C library.
+var runtimeSignals = SigSetOf(SIGKILL, SIGSTOP, SIGURG, 32, 33)
+
+// NewSignalFDInterop creates a signalfd for mask in coordination with the
+// Go runtime's signal handling. The signals must already be blocked on the
+// calling thread, which must stay locked with runtime.LockOSThread and be
+// the one reading the signalfd.
+//
+// Creation fails before any process-wide state is changed, instead of
+// returning a signalfd that would never fire:
+//   - ErrInvalidParam if mask contains a signal that cannot be caught or is
+//     used by the Go runtime: SIGKILL, SIGSTOP, SIGURG, 32 or 33.
+//   - ErrSignalIgnored if a signal is ignored, e.g. SIGHUP inherited as
+//     ignored from a nohup parent or set by os/signal.Ignore; the kernel
+//     discards ignored signals even while they are blocked.
+//   - ErrSignalNotBlocked if a signal is not blocked on the calling thread.
+//
+// Signals directed to the calling thread are accepted directly. A signal
+// sent to the process is usually taken by the Go runtime on another
+// thread, so the signals in mask are registered with os/signal.Notify for
+// as long as the signalfd is open, and those received are sent on to the
+// calling thread with tgkill(2). Such a signal is read with the sender of
+// the forwarding, not the original one, and standard signals arriving in
+// quick succession may be merged. Other channels registered with
+// os/signal.Notify still receive process-directed signals. Close calls
+// os/signal.Stop, which hands the signals back to the Go runtime: signals
+// without other channels revert to their default action.
+func NewSignalFDInterop(mask SigSet) (*SignalFD, error) {
+	if !mask.Intersect(runtimeSignals).Empty() {
+		return nil, ErrInvalidParam
+	}
+	for sig := range mask.Signals() {
+		handler, err := signalHandler(sig)
+		if err != nil {
+			return nil, err
+		}
+		if handler == SIG_IGN {
+			return nil, ErrSignalIgnored
+		}
+	}
+	cur, err := CurrentMask()
+	if err != nil {
+		return nil, err
+	}
+	if !(mask &^ cur).Empty() {
+		return nil, ErrSignalNotBlocked
+	}
+	s, err := newSignalFD(mask, SFD_NONBLOCK|SFD_CLOEXEC)
+	if err != nil {
+		return nil, err
+	}
+	s.forward = forwardSignals(mask, gettid())
+	return s, nil
+}
+
+// signalForwarder sends the process-directed signals received through
+// os/signal.Notify on to the thread owning an interop signalfd.
+type signalForwarder struct {
+	ch   chan os.Signal
+	done chan struct{}
+}
+
+// forwardSignals registers the signals in mask with os/signal.Notify and
+// starts forwarding them to thread tid.
+func forwardSignals(mask SigSet, tid int) *signalForwarder {
+	var sigs []os.Signal
+	for sig := range mask.Signals() {
+		sigs = append(sigs, syscall.Signal(sig))
+	}
+	f := &signalForwarder{
+		ch:   make(chan os.Signal, 64),
+		done: make(chan struct{}),
+	}
+	signal.Notify(f.ch, sigs...)
+	go f.run(os.Getpid(), tid)
+	return f
+}
+
+func (f *signalForwarder) run(pid, tid int) {
+	defer close(f.done)
+	for sig := range f.ch {
+		// The owning thread blocks sig, which stays pending for the signalfd
+		_, _ = zcall.Syscall4(SYS_TGKILL, uintptr(pid), uintptr(tid), uintptr(sig.(syscall.Signal)), 0)
+	}
+}
+
+// stop hands the signals back to the Go runtime and waits for the
+// forwarding goroutine to exit.
+func (f *signalForwarder) stop() {
+	signal.Stop(f.ch)
+	// No more signals are sent on f.ch once Stop returns
+	close(f.ch)
+	<-f.done
+}
+
+// sigactionBuf receives struct sigaction as used by rt_sigaction(2).
+// sa_handler is the first field on all architectures; the buffer is large
+// enough for layouts with and without sa_restorer.
+type sigactionBuf struct {
+	handler uintptr
+	_       [3]uint64
+}
+
+// signalHandler returns the current disposition of sig: SIG_DFL, SIG_IGN
+// or the address of a handler.
+func signalHandler(sig int) (uintptr, error) {
+	var act sigactionBuf
+	_, errno := zcall.Syscall4(
+		SYS_RT_SIGACTION,
+		uintptr(sig),
+		0, // query only
+		uintptr(unsafe.Pointer(&act)),
+		8, // sizeof(sigset_t)
+	)
+	if errno != 0 {
+		return 0, errFromErrno(errno)
+	}
+	return act.handler, nil
+}
+
+// Signal dispositions
+const (
+	SIG_DFL = 0
+	SIG_IGN = 1
+)
@@ -0,0 +1,242 @@
+// ©Hayabusa Cloud Co., Ltd. 2025. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+//go:build linux
+
+package iofd
+
+import (
+	"fmt"
+	"iter"
+	"math/bits"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// SigSetOf returns a set containing the given signals.
+// Signals outside 1..64 are ignored.
+func SigSetOf(sigs ...int) SigSet {
+	var s SigSet
+	for _, sig := range sigs {
+		s.Add(sig)
+	}
+	return s
+}
+
+// Union returns the signals in s or o.
+func (s SigSet) Union(o SigSet) SigSet {
+	return s | o
+}
+
+// Intersect returns the signals in both s and o.
+func (s SigSet) Intersect(o SigSet) SigSet {
+	return s & o
+}
+
+// Complement returns all signals 1..64 not in s.
+// The result includes SIGKILL and SIGSTOP, which can be neither blocked
+// nor accepted through a signalfd.
+func (s SigSet) Complement() SigSet {
+	return ^s
+}
+
+// Signals returns an iterator over the signals in s in ascending order.
+func (s SigSet) Signals() iter.Seq[int] {
+	return func(yield func(int) bool) {
+		for rest := uint64(s); rest != 0; rest &= rest - 1 {
+			if !yield(bits.TrailingZeros64(rest) + 1) {
+				return
+			}
+		}
+	}
+}
+
+// String returns the signal names in ascending order,
+// e.g. "{SIGINT, SIGTERM, SIGRTMIN+3}".
+func (s SigSet) String() string {
+	var b strings.Builder
+	b.WriteByte('{')
+	for sig := range s.Signals() {
+		if b.Len() > 1 {
+			b.WriteString(", ")
+		}
+		b.WriteString(SignalName(sig))
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+// Format implements fmt.Formatter. The %v, %s and %q verbs print the
+// signal names as String does; other verbs, and %#v, print the raw mask
+// as they would a uint64, so that %x yields the mask in hexadecimal.
+func (s SigSet) Format(f fmt.State, verb rune) {
+	switch {
+	case verb == 's' || verb == 'q' || verb == 'v' && !f.Flag('#'):
+		if verb == 'v' {
+			verb = 's'
+		}
+		fmt.Fprintf(f, fmt.FormatString(f, verb), s.String())
+	default:
+		fmt.Fprintf(f, fmt.FormatString(f, verb), uint64(s))
+	}
+}
+
+// Real-time signals are numbered 32..64 by the kernel, but the C library
+// reserves the lowest ones for its threading implementation: glibc uses 32
+// and 33, musl 32 to 34. The Go runtime also treats 32 and 33 as reserved.
+var sigrtmin = sync.OnceValue(func() int {
+	// A dynamically linked musl program maps the musl dynamic loader
+	if maps, err := readFile("/proc/self/maps"); err == nil && strings.Contains(string(maps), "/ld-musl-") {
+		return 35
+	}
+	return 34
+})
+
+// SIGRTMIN returns the lowest real-time signal available to applications:
+// 34, or 35 in a program linked against musl.
+//
+// musl is detected by its dynamic loader in /proc/self/maps, so a
+// statically linked musl program gets 34, which musl reserves as well.
+// Such programs should start their real-time signals at SIGRTMIN()+1.
+//
+// Unlike standard signals, real-time signals are queued rather than
+// merged, and pending ones are delivered lowest number first.
+func SIGRTMIN() int {
+	return sigrtmin()
+}
+
+// SIGRTMAX returns the highest real-time signal.
+func SIGRTMAX() int {
+	return 64
+}
+
+// signalNames maps standard signal numbers to their names.
+var signalNames = [...]string{
+	SIGHUP:    "SIGHUP",
+	SIGINT:    "SIGINT",
+	SIGQUIT:   "SIGQUIT",
+	SIGILL:    "SIGILL",
+	SIGTRAP:   "SIGTRAP",
+	SIGABRT:   "SIGABRT",
+	SIGBUS:    "SIGBUS",
+	SIGFPE:    "SIGFPE",
+	SIGKILL:   "SIGKILL",
+	SIGUSR1:   "SIGUSR1",
+	SIGSEGV:   "SIGSEGV",
+	SIGUSR2:   "SIGUSR2",
+	SIGPIPE:   "SIGPIPE",
+	SIGALRM:   "SIGALRM",
+	SIGTERM:   "SIGTERM",
+	SIGSTKFLT: "SIGSTKFLT",
+	SIGCHLD:   "SIGCHLD",
+	SIGCONT:   "SIGCONT",
+	SIGSTOP:   "SIGSTOP",
+	SIGTSTP:   "SIGTSTP",
+	SIGTTIN:   "SIGTTIN",
+	SIGTTOU:   "SIGTTOU",
+	SIGURG:    "SIGURG",
+	SIGXCPU:   "SIGXCPU",
+	SIGXFSZ:   "SIGXFSZ",
+	SIGVTALRM: "SIGVTALRM",
+	SIGPROF:   "SIGPROF",
+	SIGWINCH:  "SIGWINCH",
+	SIGIO:     "SIGIO",
+	SIGPWR:    "SIGPWR",
+	SIGSYS:    "SIGSYS",
+}
+
+// signalAliases maps alternative names to signal numbers.
+var signalAliases = map[string]int{
+	"SIGIOT":  SIGABRT,
+	"SIGCLD":  SIGCHLD,
+	"SIGPOLL": SIGIO,
+}
+
+// SignalName returns the name of sig: "SIGTERM" for standard signals,
+// "SIGRTMIN+n" or "SIGRTMAX" for real-time signals, and "SIGn" for
+// signals reserved by the C library. Other values return "signal n".
+func SignalName(sig int) string {
+	switch {
+	case sig > 0 && sig < len(signalNames):
+		return signalNames[sig]
+	case sig == SIGRTMAX():
+		return "SIGRTMAX"
+	case sig == SIGRTMIN():
+		return "SIGRTMIN"
+	case sig > SIGRTMIN() && sig < SIGRTMAX():
+		return "SIGRTMIN+" + strconv.Itoa(sig-SIGRTMIN())
+	case sig >= len(signalNames) && sig < SIGRTMIN():
+		return "SIG" + strconv.Itoa(sig)
+	default:
+		return "signal " + strconv.Itoa(sig)
+	}
+}
+
+// ParseSignal parses a signal name or number as found in configuration
+// files. Names are case-insensitive and the "SIG" prefix is optional:
+// "SIGTERM", "term", "RTMIN+3", "SIGRTMAX-1" and "15" are all accepted,
+// as are the names returned by SignalName.
+func ParseSignal(s string) (int, error) {
+	name := strings.ToUpper(strings.TrimSpace(s))
+	if n, err := strconv.Atoi(name); err == nil {
+		if n < 1 || n > 64 {
+			return 0, ErrInvalidParam
+		}
+		return n, nil
+	}
+	if !strings.HasPrefix(name, "SIG") {
+		name = "SIG" + name
+	}
+	for sig, n := range signalNames {
+		if n == name {
+			return sig, nil
+		}
+	}
+	if sig, ok := signalAliases[name]; ok {
+		return sig, nil
+	}
+	if sig, ok := parseRealtime(name); ok {
+		return sig, nil
+	}
+	return 0, ErrInvalidParam
+}
+
+// parseRealtime parses "SIGRTMIN", "SIGRTMIN+n", "SIGRTMAX", "SIGRTMAX-n"
+// and reserved "SIGn" names.
+func parseRealtime(name string) (int, bool) {
+	var base, sign int
+	var rest string
+	switch {
+	case strings.HasPrefix(name, "SIGRTMIN"):
+		base, sign, rest = SIGRTMIN(), 1, name[len("SIGRTMIN"):]
+	case strings.HasPrefix(name, "SIGRTMAX"):
+		base, sign, rest = SIGRTMAX(), -1, name[len("SIGRTMAX"):]
+	default:
+		n, err := strconv.Atoi(name[len("SIG"):])
+		if err != nil || n < len(signalNames) || n > 64 {
+			return 0, false
+		}
+		return n, true
+	}
+	if rest == "" {
+		return base, true
+	}
+	op := "+"
+	if sign < 0 {
+		op = "-"
+	}
+	if !strings.HasPrefix(rest, op) {
+		return 0, false
+	}
+	n, err := strconv.ParseUint(rest[1:], 10, 8)
+	if err != nil {
+		return 0, false
+	}
+	sig := base + sign*int(n)
+	if sig < SIGRTMIN() || sig > SIGRTMAX() {
+		return 0, false
+	}
+	return sig, true
+}
@@ -0,0 +1,221 @@
+// ©Hayabusa Cloud Co., Ltd. 2025. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+//go:build linux
+
+package iofd
+
+import (
+	"sync"
+	"time"
+)
+
+// Ticker is a periodic timer on a TimerFD, the pollable counterpart of
+// time.Ticker.
+//
+// Ticks are scheduled at absolute times start+k*period on CLOCK_MONOTONIC,
+// so the schedule does not drift however late the ticks are read. Ticks
+// that elapse before the previous one is read are not queued: Read reports
+// them together and Missed accumulates them.
+type Ticker struct {
+	mu     sync.Mutex
+	timer  *TimerFD
+	period int64
+	ticks  uint64
+	missed uint64
+}
+
+// NewTicker creates a Ticker that ticks every d, starting d from now.
+// Returns ErrInvalidParam if d is not positive.
+func NewTicker(d time.Duration) (*Ticker, error) {
+	if d <= 0 {
+		return nil, ErrInvalidParam
+	}
+	timer, err := NewTimerFD()
+	if err != nil {
+		return nil, err
+	}
+	t := &Ticker{timer: timer}
+	if err := t.Reset(d); err != nil {
+		_ = timer.Close()
+		return nil, err
+	}
+	return t, nil
+}
+
+// Fd returns the underlying timerfd.
+// Implements PollFd interface.
+func (t *Ticker) Fd() int {
+	return t.timer.Fd()
+}
+
+// Close closes the timerfd.
+// Implements PollCloser interface.
+func (t *Ticker) Close() error {
+	return t.timer.Close()
+}
+
+// Read consumes the ticks elapsed since the last read and returns their
+// number. All but one of them count as missed.
+// Returns iox.ErrWouldBlock if no tick has elapsed.
+func (t *Ticker) Read() (uint64, error) {
+	n, err := t.timer.Read()
+	if err != nil {
+		return 0, err
+	}
+	t.mu.Lock()
+	t.ticks += n
+	t.missed += n - 1
+	t.mu.Unlock()
+	return n, nil
+}
+
+// Ticks returns the total number of ticks consumed by Read.
+func (t *Ticker) Ticks() uint64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.ticks
+}
+
+// Missed returns the total number of ticks that elapsed while an earlier
+// tick was still unread.
+func (t *Ticker) Missed() uint64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.missed
+}
+
+// Period returns the current tick period, or 0 if the ticker is stopped.
+func (t *Ticker) Period() time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return time.Duration(t.period)
+}
+
+// Reset stops the ticker and restarts it with period d, the next tick
+// being d from now. Ticks elapsed but not yet read are discarded.
+// Returns ErrInvalidParam if d is not positive.
+func (t *Ticker) Reset(d time.Duration) error {
+	if d <= 0 {
+		return ErrInvalidParam
+	}
+	now, err := Now(CLOCK_MONOTONIC)
+	if err != nil {
+		return err
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if err := t.timer.ArmAt(deadlineAfter(now, d), int64(d)); err != nil {
+		return err
+	}
+	t.period = int64(d)
+	return nil
+}
+
+// Stop turns off the ticker, discarding ticks not yet read. The descriptor
+// stays open; Reset starts the ticker again.
+func (t *Ticker) Stop() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if err := t.timer.Disarm(); err != nil {
+		return err
+	}
+	t.period = 0
+	return nil
+}
+
+// FuncTimer runs a function once on a TimerFD, the pollable counterpart of
+// the time.Timer returned by time.AfterFunc.
+//
+// No goroutine is started: the function runs on the goroutine that calls
+// Read once the descriptor has become readable.
+type FuncTimer struct {
+	mu     sync.Mutex
+	timer  *TimerFD
+	f      func()
+	active bool
+}
+
+// AfterFunc creates a FuncTimer that runs f in Read once d has elapsed.
+// A non-positive d makes the descriptor readable at once.
+func AfterFunc(d time.Duration, f func()) (*FuncTimer, error) {
+	if f == nil {
+		return nil, ErrInvalidParam
+	}
+	timer, err := NewTimerFD()
+	if err != nil {
+		return nil, err
+	}
+	t := &FuncTimer{timer: timer, f: f}
+	if _, err := t.Reset(d); err != nil {
+		_ = timer.Close()
+		return nil, err
+	}
+	return t, nil
+}
+
+// Fd returns the underlying timerfd.
+// Implements PollFd interface.
+func (t *FuncTimer) Fd() int {
+	return t.timer.Fd()
+}
+
+// Close closes the timerfd. The function does not run any more.
+// Implements PollCloser interface.
+func (t *FuncTimer) Close() error {
+	return t.timer.Close()
+}
+
+// Read runs the function on the calling goroutine if the timer has expired
+// and has not been stopped since, and reports whether it ran.
+// Returns iox.ErrWouldBlock if the timer has not expired.
+func (t *FuncTimer) Read() (bool, error) {
+	t.mu.Lock()
+	if _, err := t.timer.Read(); err != nil {
+		t.mu.Unlock()
+		return false, err
+	}
+	run := t.active
+	t.active = false
+	t.mu.Unlock()
+	if run {
+		t.f()
+	}
+	return run, nil
+}
+
+// Stop prevents the function from running. Returns false if it has
+// already run or been stopped. An expiration not yet read is discarded.
+func (t *FuncTimer) Stop() (bool, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if err := t.timer.Disarm(); err != nil {
+		return false, err
+	}
+	active := t.active
+	t.active = false
+	return active, nil
+}
+
+// Reset makes the function run once d has elapsed from now, whether or
+// not it is still pending, and reports whether it was pending as Stop does.
+func (t *FuncTimer) Reset(d time.Duration) (bool, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	// The timerfd treats 0 as disarm, so the earliest expiration is 1ns
+	if err := t.timer.Arm(max(int64(d), 1), 0); err != nil {
+		return false, err
+	}
+	active := t.active
+	t.active = true
+	return active, nil
+}
+
+// Compile-time interface assertions
+var (
+	_ PollFd     = (*Ticker)(nil)
+	_ PollCloser = (*Ticker)(nil)
+	_ PollFd     = (*FuncTimer)(nil)
+	_ PollCloser = (*FuncTimer)(nil)
+)
@@ -0,0 +1,429 @@
+// ©Hayabusa Cloud Co., Ltd. 2025. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+//go:build linux
+
+package iofd
+
+import (
+	"cmp"
+	"math"
+	"math/bits"
+	"slices"
+	"sync"
+	"time"
+
+	"code.hybscloud.com/iox"
+)
+
+// Geometry of the timer wheel: each level has 64 slots, and a slot of
+// level l spans 64^l ticks, so eight levels cover 2^48 ticks.
+const (
+	wheelBits   = 6
+	wheelSlots  = 1 << wheelBits
+	wheelLevels = 8
+
+	// wheelMaxDelta bounds the distance of a placement so that it fits the
+	// top level; later deadlines are placed at the bound and moved on
+	// when it is reached.
+	wheelMaxDelta = (wheelSlots - 2) << (wheelBits * (wheelLevels - 1))
+)
+
+// TimerWheel multiplexes many deadlines onto a single TimerFD.
+//
+// Deadlines are kept in a hashed hierarchical timing wheel on
+// CLOCK_MONOTONIC, quantized to a tick. Adding and stopping a timer takes
+// constant time; the TimerFD is armed with ArmAt for the nearest tick at
+// which a timer expires or the wheel must redistribute a slot.
+//
+// Fd returns the timerfd for use in an external event loop: call Read
+// whenever it becomes readable to run the callbacks of expired timers.
+type TimerWheel struct {
+	mu     sync.Mutex // guards the wheel and the timers
+	timer  *TimerFD
+	tick   int64 // Tick length in nanoseconds
+	cur    int64 // Last processed tick
+	seq    uint64
+	count  int
+	armed  int64     // Tick the timerfd is armed for, or -1
+	due    wheelList // Timers added after their tick was processed
+	levels [wheelLevels]wheelLevel
+
+	rmu     sync.Mutex // serializes Read
+	expired []*WheelTimer
+}
+
+// wheelLevel is one level of the wheel; bit i of occupied is set while
+// slot i is not empty.
+type wheelLevel struct {
+	occupied uint64
+	slots    [wheelSlots]wheelList
+}
+
+// wheelList is an intrusive doubly linked list of timers.
+type wheelList struct {
+	head, tail *WheelTimer
+}
+
+// WheelTimer is a timer scheduled on a TimerWheel.
+type WheelTimer struct {
+	w          *TimerWheel
+	f          func()
+	prev, next *WheelTimer
+	deadline   int64 // CLOCK_MONOTONIC nanoseconds
+	expires    int64 // Tick of deadline, rounded up
+	seq        uint64
+	level      int8
+	slot       int8
+	state      uint8
+}
+
+// WheelTimer states
+const (
+	wheelIdle    = iota // Not scheduled: stopped or already run
+	wheelPending        // Linked into a slot
+	wheelFiring         // Expired and about to run
+)
+
+// NewTimerWheel creates a TimerWheel with the given tick length, which is
+// the resolution of its deadlines. Returns ErrInvalidParam if tick is not
+// positive.
+func NewTimerWheel(tick time.Duration) (*TimerWheel, error) {
+	if tick <= 0 {
+		return nil, ErrInvalidParam
+	}
+	now, err := Now(CLOCK_MONOTONIC)
+	if err != nil {
+		return nil, err
+	}
+	timer, err := NewTimerFD()
+	if err != nil {
+		return nil, err
+	}
+	return &TimerWheel{timer: timer, tick: int64(tick), cur: now / int64(tick), armed: -1}, nil
+}
+
+// Fd returns the underlying timerfd.
+// Implements PollFd interface.
+func (w *TimerWheel) Fd() int {
+	return w.timer.Fd()
+}
+
+// Close closes the timerfd. Pending timers never run.
+// Implements PollCloser interface.
+func (w *TimerWheel) Close() error {
+	return w.timer.Close()
+}
+
+// Len returns the number of pending timers.
+func (w *TimerWheel) Len() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.count
+}
+
+// Add schedules f to run once d has elapsed, rounded up to the next tick.
+// A non-positive d runs f on the next Read.
+func (w *TimerWheel) Add(d time.Duration, f func()) (*WheelTimer, error) {
+	now, err := Now(CLOCK_MONOTONIC)
+	if err != nil {
+		return nil, err
+	}
+	return w.addAt(deadlineAfter(now, d), f)
+}
+
+// AddAt schedules f to run at deadline, rounded up to the next tick.
+// A deadline in the past runs f on the next Read.
+func (w *TimerWheel) AddAt(deadline time.Time, f func()) (*WheelTimer, error) {
+	if deadline.IsZero() {
+		return nil, ErrInvalidParam
+	}
+	return w.Add(time.Until(deadline), f)
+}
+
+func (w *TimerWheel) addAt(deadline int64, f func()) (*WheelTimer, error) {
+	if f == nil {
+		return nil, ErrInvalidParam
+	}
+	t := &WheelTimer{w: w, f: f}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timer.fd.Raw() < 0 {
+		return nil, ErrClosed
+	}
+	w.start(t, deadline)
+	return t, w.rearm()
+}
+
+// start schedules an idle timer for deadline.
+func (w *TimerWheel) start(t *WheelTimer, deadline int64) {
+	w.seq++
+	t.seq = w.seq
+	t.deadline = deadline
+	t.expires = ceilDiv(deadline, w.tick)
+	w.count++
+	w.place(t)
+}
+
+// place links a timer into the slot for its expiry relative to the
+// current tick, or into the due list if that tick has been processed.
+func (w *TimerWheel) place(t *WheelTimer) {
+	list := &w.due
+	t.level, t.slot = -1, 0
+	if t.expires > w.cur {
+		expires := min(t.expires, w.cur+wheelMaxDelta)
+		level := 0
+		for ; level < wheelLevels-1; level++ {
+			shift := uint(wheelBits * level)
+			if expires>>shift-w.cur>>shift < wheelSlots {
+				break
+			}
+		}
+		slot := int(expires>>(wheelBits*level)) & (wheelSlots - 1)
+		w.levels[level].occupied |= 1 << slot
+		list = &w.levels[level].slots[slot]
+		t.level, t.slot = int8(level), int8(slot)
+	}
+	t.prev, t.next = list.tail, nil
+	if list.tail != nil {
+		list.tail.next = t
+	} else {
+		list.head = t
+	}
+	list.tail = t
+	t.state = wheelPending
+}
+
+// unlink removes a pending timer from its slot.
+func (w *TimerWheel) unlink(t *WheelTimer) {
+	list := &w.due
+	if t.level >= 0 {
+		list = &w.levels[t.level].slots[t.slot]
+	}
+	if t.prev != nil {
+		t.prev.next = t.next
+	} else {
+		list.head = t.next
+	}
+	if t.next != nil {
+		t.next.prev = t.prev
+	} else {
+		list.tail = t.prev
+	}
+	if list.head == nil && t.level >= 0 {
+		w.levels[t.level].occupied &^= 1 << t.slot
+	}
+	t.prev, t.next = nil, nil
+	t.state = wheelIdle
+}
+
+// next returns the next tick at which a timer expires or a slot must be
+// redistributed to a lower level, or -1 if the wheel is empty.
+func (w *TimerWheel) next() int64 {
+	if w.due.head != nil {
+		return w.cur
+	}
+	next := int64(-1)
+	for level := range wheelLevels {
+		occupied := w.levels[level].occupied
+		if occupied == 0 {
+			continue
+		}
+		shift := uint(wheelBits * level)
+		base := w.cur >> shift
+		// Distance in slots from the current one, which is never occupied
+		d := int64(bits.TrailingZeros64(bits.RotateLeft64(occupied, -int((base+1)&(wheelSlots-1))))) + 1
+		at := (base + d) << shift
+		if next < 0 || at < next {
+			next = at
+		}
+	}
+	return next
+}
+
+// advance processes all ticks up to now and appends the expired timers
+// to w.expired in no particular order.
+func (w *TimerWheel) advance(now int64) {
+	for t := w.due.head; t != nil; {
+		nt := t.next
+		w.fire(t)
+		t = nt
+	}
+	for {
+		next := w.next()
+		if next < 0 || next > now {
+			break
+		}
+		w.cur = next
+		// Redistribute the slots whose span begins at this tick, from the
+		// top down so that timers can move down several levels
+		for level := wheelLevels - 1; level >= 0; level-- {
+			shift := uint(wheelBits * level)
+			if level > 0 && w.cur&(1<<shift-1) != 0 {
+				continue
+			}
+			slot := int(w.cur>>shift) & (wheelSlots - 1)
+			l := &w.levels[level]
+			if l.occupied&(1<<slot) == 0 {
+				continue
+			}
+			for t := l.slots[slot].head; t != nil; {
+				nt := t.next
+				if t.expires <= w.cur {
+					w.fire(t)
+				} else {
+					w.unlink(t)
+					w.place(t)
+				}
+				t = nt
+			}
+		}
+	}
+	w.cur = max(w.cur, now)
+}
+
+// fire moves a pending timer to the expired list.
+func (w *TimerWheel) fire(t *WheelTimer) {
+	w.unlink(t)
+	t.state = wheelFiring
+	w.count--
+	w.expired = append(w.expired, t)
+}
+
+// rearm arms the timerfd for the next tick of the wheel if it changed.
+func (w *TimerWheel) rearm() error {
+	next := w.next()
+	if next == w.armed {
+		return nil
+	}
+	w.armed = next
+	if next < 0 {
+		return w.timer.Disarm()
+	}
+	deadline := int64(clockWatchDeadline)
+	if next < deadline/w.tick {
+		deadline = max(next*w.tick, 1)
+	}
+	return w.timer.ArmAt(deadline, 0)
+}
+
+// Read processes the expired timers and runs their callbacks on the
+// calling goroutine, in order of deadline and, for equal deadlines, in the
+// order they were added. Returns the number of callbacks run.
+//
+// Callbacks may add, stop and reset timers, but must not call Read.
+func (w *TimerWheel) Read() (int, error) {
+	w.rmu.Lock()
+	defer w.rmu.Unlock()
+
+	w.mu.Lock()
+	if _, err := w.timer.Read(); err != nil && err != iox.ErrWouldBlock {
+		w.mu.Unlock()
+		return 0, err
+	}
+	now, err := Now(CLOCK_MONOTONIC)
+	if err != nil {
+		w.mu.Unlock()
+		return 0, err
+	}
+	w.armed = -1 // A read timerfd is no longer armed
+	w.advance(now / w.tick)
+	err = w.rearm()
+	w.mu.Unlock()
+
+	slices.SortFunc(w.expired, func(a, b *WheelTimer) int {
+		return cmp.Or(cmp.Compare(a.deadline, b.deadline), cmp.Compare(a.seq, b.seq))
+	})
+	n := 0
+	for i, t := range w.expired {
+		w.expired[i] = nil
+		// Skip timers stopped or reset by an earlier callback
+		w.mu.Lock()
+		run := t.state == wheelFiring
+		if run {
+			t.state = wheelIdle
+		}
+		w.mu.Unlock()
+		if run {
+			t.f()
+			n++
+		}
+	}
+	w.expired = w.expired[:0]
+	return n, err
+}
+
+// Deadline returns the CLOCK_MONOTONIC time in nanoseconds at which the
+// timer was last scheduled to expire.
+func (t *WheelTimer) Deadline() int64 {
+	t.w.mu.Lock()
+	defer t.w.mu.Unlock()
+	return t.deadline
+}
+
+// Stop prevents the timer from running. Returns false if it has already
+// run or been stopped.
+func (t *WheelTimer) Stop() bool {
+	w := t.w
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	switch t.state {
+	case wheelPending:
+		w.unlink(t)
+		w.count--
+		return true
+	case wheelFiring:
+		t.state = wheelIdle
+		return true
+	}
+	return false
+}
+
+// Reset reschedules the timer to run once d has elapsed, whether or not it
+// is still pending. Returns whether it was pending, as Stop does.
+func (t *WheelTimer) Reset(d time.Duration) (bool, error) {
+	now, err := Now(CLOCK_MONOTONIC)
+	if err != nil {
+		return false, err
+	}
+	w := t.w
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timer.fd.Raw() < 0 {
+		return false, ErrClosed
+	}
+	pending := t.state != wheelIdle
+	if t.state == wheelPending {
+		w.unlink(t)
+		w.count--
+	}
+	// A firing timer is rescheduled in place; Read skips it since it is
+	// no longer in the firing state
+	w.start(t, deadlineAfter(now, d))
+	return pending, w.rearm()
+}
+
+// deadlineAfter returns now+d, with a negative d counting as 0 and
+// saturating on overflow.
+func deadlineAfter(now int64, d time.Duration) int64 {
+	if int64(d) > math.MaxInt64-now {
+		return math.MaxInt64
+	}
+	return now + max(int64(d), 0)
+}
+
+// ceilDiv returns a/b rounded up for b > 0.
+func ceilDiv(a, b int64) int64 {
+	q := a / b
+	if a%b > 0 {
+		q++
+	}
+	return q
+}
+
+// Compile-time interface assertions
+var (
+	_ PollFd     = (*TimerWheel)(nil)
+	_ PollCloser = (*TimerWheel)(nil)
+)
@@ -7,7 +7,9 @@
 package iofd
 
 import (
+	"context"
 	"encoding/binary"
+	"math"
 	"time"
 	"unsafe"
 
@@ -20,7 +22,9 @@ import (
 //
 // TimerFD is created with TFD_NONBLOCK and TFD_CLOEXEC by default.
 type TimerFD struct {
-	fd FD
+	fd       FD
+	clockid  int
+	blocking bool
 }
 
 // NewTimerFD creates a new timerfd using CLOCK_MONOTONIC.
@@ -41,12 +45,41 @@ func NewTimerFDBoottime() (*TimerFD, error) {
 	return newTimerFD(CLOCK_BOOTTIME, TFD_NONBLOCK|TFD_CLOEXEC)
 }
 
+// NewTimerFDAlarm creates a new timerfd using CLOCK_REALTIME_ALARM.
+// Like CLOCK_REALTIME, but an armed timer wakes the system from suspend.
+// Requires CAP_WAKE_ALARM; returns ErrPermission otherwise.
+func NewTimerFDAlarm() (*TimerFD, error) {
+	return newTimerFD(CLOCK_REALTIME_ALARM, TFD_NONBLOCK|TFD_CLOEXEC)
+}
+
+// NewTimerFDBoottimeAlarm creates a new timerfd using CLOCK_BOOTTIME_ALARM.
+// Like CLOCK_BOOTTIME, but an armed timer wakes the system from suspend.
+// Requires CAP_WAKE_ALARM; returns ErrPermission otherwise.
+func NewTimerFDBoottimeAlarm() (*TimerFD, error) {
+	return newTimerFD(CLOCK_BOOTTIME_ALARM, TFD_NONBLOCK|TFD_CLOEXEC)
+}
+
+// NewTimerFDClock creates a new timerfd for any clock supported by
+// timerfd_create(2) with the given TFD_* flags. CPU-time clocks are not
+// supported; use CPUBudget or PosixTimer for them.
+//
+// Without TFD_NONBLOCK, Read waits until the timer expires instead of
+// returning iox.ErrWouldBlock; ReadContext can also be canceled. Both park
+// in ppoll(2) rather than in read(2), which the Go runtime could not
+// interrupt.
+func NewTimerFDClock(clockid, flags int) (*TimerFD, error) {
+	if clockid < 0 || flags&^(TFD_NONBLOCK|TFD_CLOEXEC) != 0 {
+		return nil, ErrInvalidParam
+	}
+	return newTimerFD(uintptr(clockid), uintptr(flags))
+}
+
 func newTimerFD(clockid, flags uintptr) (*TimerFD, error) {
 	fd, errno := zcall.TimerfdCreate(clockid, flags)
 	if errno != 0 {
 		return nil, errFromErrno(errno)
 	}
-	return &TimerFD{fd: FD(fd)}, nil
+	return &TimerFD{fd: FD(fd), clockid: int(clockid), blocking: flags&TFD_NONBLOCK == 0}, nil
 }
 
 // Fd returns the underlying file descriptor.
@@ -55,6 +88,11 @@ func (t *TimerFD) Fd() int {
 	return t.fd.Fd()
 }
 
+// Clock returns the clock ID the timer measures, e.g. CLOCK_MONOTONIC.
+func (t *TimerFD) Clock() int {
+	return t.clockid
+}
+
 // Close closes the timerfd.
 // Implements PollCloser interface.
 func (t *TimerFD) Close() error {
@@ -63,63 +101,137 @@ func (t *TimerFD) Close() error {
 
 // Arm sets the timer to expire after initial nanoseconds.
 // If interval is non-zero, the timer repeats with that interval (in nanoseconds).
+// Returns ErrInvalidParam if either value is negative.
 //
 // Parameters:
 //   - initial: time until first expiration in nanoseconds (0 disarms)
 //   - interval: interval for periodic timer in nanoseconds (0 for one-shot)
 func (t *TimerFD) Arm(initial, interval int64) error {
-	raw := t.fd.Raw()
-	if raw < 0 {
-		return ErrClosed
-	}
-	newValue := itimerspec{
-		interval: timespec{
-			sec:  interval / 1e9,
-			nsec: interval % 1e9,
-		},
-		value: timespec{
-			sec:  initial / 1e9,
-			nsec: initial % 1e9,
-		},
-	}
-	errno := zcall.TimerfdSettime(
-		uintptr(raw),
-		0, // relative time
-		unsafe.Pointer(&newValue),
-		nil, // don't need old value
-	)
-	if errno != 0 {
-		return errFromErrno(errno)
-	}
-	return nil
+	return t.settime(0, initial, interval, nil)
 }
 
-// ArmAt sets the timer to expire at an absolute time.
+// ArmAt sets the timer to expire at an absolute time of its clock.
 // If interval is non-zero, the timer repeats with that interval (in nanoseconds).
+// Returns ErrInvalidParam if either value is negative.
 //
 // Parameters:
-//   - deadline: absolute time for first expiration (Unix nanoseconds)
+//   - deadline: absolute time for first expiration in nanoseconds of the
+//     timer's clock: Unix nanoseconds for CLOCK_REALTIME, time since boot
+//     for CLOCK_MONOTONIC and CLOCK_BOOTTIME (0 disarms). Use ArmAtTime to
+//     arm with a time.Time on any clock.
 //   - interval: interval for periodic timer in nanoseconds (0 for one-shot)
 func (t *TimerFD) ArmAt(deadline, interval int64) error {
+	return t.settime(TFD_TIMER_ABSTIME, deadline, interval, nil)
+}
+
+// ArmAtTime sets the timer to expire at deadline, converted to the timer's
+// clock. If interval is non-zero, the timer repeats with that interval.
+//
+// Timers on CLOCK_REALTIME and CLOCK_REALTIME_ALARM use the wall time of
+// deadline. For the other clocks, the time remaining until deadline, as
+// measured by time.Until, is added to the current time of the clock. A
+// deadline in the past expires immediately. Returns ErrInvalidParam for a
+// zero deadline, a negative interval, or a deadline that does not fit the
+// clock's range.
+func (t *TimerFD) ArmAtTime(deadline time.Time, interval time.Duration) error {
+	ns, err := t.clockTime(deadline)
+	if err != nil {
+		return err
+	}
+	return t.settime(TFD_TIMER_ABSTIME, ns, int64(interval), nil)
+}
+
+// clockTime converts deadline to nanoseconds of the timer's clock.
+func (t *TimerFD) clockTime(deadline time.Time) (int64, error) {
+	if deadline.IsZero() {
+		return 0, ErrInvalidParam
+	}
+	var ns int64
+	if t.clockid == CLOCK_REALTIME || t.clockid == CLOCK_REALTIME_ALARM {
+		// UnixNano is undefined outside of int64 nanoseconds; a deadline
+		// before the epoch has passed anyway
+		switch sec := deadline.Unix(); {
+		case sec >= math.MaxInt64/int64(time.Second):
+			return 0, ErrInvalidParam
+		case sec >= 0:
+			ns = deadline.UnixNano()
+		}
+	} else {
+		now, err := Now(t.clockid)
+		if err != nil {
+			return 0, err
+		}
+		d := int64(time.Until(deadline))
+		if d > math.MaxInt64-now {
+			return 0, ErrInvalidParam
+		}
+		ns = now + d
+	}
+	// A zero deadline would disarm the timer instead of expiring it
+	return max(ns, 1), nil
+}
+
+// ArmAtCancelOnSet is like ArmAt, but the timer is canceled when the
+// realtime clock is set discontinuously, e.g. stepped by NTP, an operator
+// or a resume from suspend. Read then returns ErrClockChanged so that the
+// caller can recompute its deadlines and arm again.
+//
+// Only timers on CLOCK_REALTIME or CLOCK_REALTIME_ALARM can be canceled;
+// returns ErrInvalidParam for any other clock.
+func (t *TimerFD) ArmAtCancelOnSet(deadline, interval int64) error {
+	if t.clockid != CLOCK_REALTIME && t.clockid != CLOCK_REALTIME_ALARM {
+		return ErrInvalidParam
+	}
+	return t.settime(TFD_TIMER_ABSTIME|TFD_TIMER_CANCEL_ON_SET, deadline, interval, nil)
+}
+
+// Rearm is like Arm, but also returns the previous setting: the time that
+// was remaining until the next expiration (0 if disarmed) and the interval,
+// in nanoseconds. Both settings are exchanged atomically.
+func (t *TimerFD) Rearm(initial, interval int64) (remaining, oldInterval int64, err error) {
+	var old itimerspec
+	if err := t.settime(0, initial, interval, &old); err != nil {
+		return 0, 0, err
+	}
+	return old.value.sec*1e9 + old.value.nsec, old.interval.sec*1e9 + old.interval.nsec, nil
+}
+
+// RearmAt is like ArmAt, but also returns the previous setting as Rearm
+// does. The remaining time is relative even though deadline is absolute.
+func (t *TimerFD) RearmAt(deadline, interval int64) (remaining, oldInterval int64, err error) {
+	var old itimerspec
+	if err := t.settime(TFD_TIMER_ABSTIME, deadline, interval, &old); err != nil {
+		return 0, 0, err
+	}
+	return old.value.sec*1e9 + old.value.nsec, old.interval.sec*1e9 + old.interval.nsec, nil
+}
+
+// RearmDuration is like ArmDuration, but also returns the previous setting
+// as Rearm does.
+func (t *TimerFD) RearmDuration(initial, interval time.Duration) (remaining, oldInterval time.Duration, err error) {
+	r, i, err := t.Rearm(int64(initial), int64(interval))
+	return time.Duration(r), time.Duration(i), err
+}
+
+// settime calls timerfd_settime(2) with the given flags, storing the
+// previous setting in old unless it is nil.
+func (t *TimerFD) settime(flags uintptr, value, interval int64, old *itimerspec) error {
 	raw := t.fd.Raw()
 	if raw < 0 {
 		return ErrClosed
 	}
+	if value < 0 || interval < 0 {
+		return ErrInvalidParam
+	}
 	newValue := itimerspec{
-		interval: timespec{
-			sec:  interval / 1e9,
-			nsec: interval % 1e9,
-		},
-		value: timespec{
-			sec:  deadline / 1e9,
-			nsec: deadline % 1e9,
-		},
+		interval: nsToTimespec(interval),
+		value:    nsToTimespec(value),
 	}
 	errno := zcall.TimerfdSettime(
 		uintptr(raw),
-		TFD_TIMER_ABSTIME,
+		flags,
 		unsafe.Pointer(&newValue),
-		nil,
+		unsafe.Pointer(old),
 	)
 	if errno != 0 {
 		return errFromErrno(errno)
@@ -138,7 +250,9 @@ func (t *TimerFD) Disarm() error {
 }
 
 // Read reads the number of expirations since the last read.
-// Returns iox.ErrWouldBlock if no expirations have occurred (non-blocking mode).
+// Returns iox.ErrWouldBlock if no expirations have occurred (non-blocking mode),
+// and ErrClockChanged if a timer armed with ArmAtCancelOnSet was canceled.
+// In blocking mode, Read parks in ppoll(2) until the timer expires.
 //
 // The returned value is the number of times the timer has expired since
 // the last successful read. For periodic timers, this may be > 1 if
@@ -149,12 +263,40 @@ func (t *TimerFD) Read() (uint64, error) {
 		return 0, ErrClosed
 	}
 	var buf [8]byte
-	n, errno := zcall.Read(uintptr(raw), buf[:])
+	n, err := readWait(raw, buf[:], t.blocking, timerFDReadError)
+	if err != nil {
+		return 0, err
+	}
+	if n != 8 {
+		return 0, ErrInvalidParam
+	}
+	return binary.NativeEndian.Uint64(buf[:]), nil
+}
+
+// ReadContext is like Read, but parks the calling goroutine until the
+// timer expires or ctx is done, returning ctx.Err() in the latter case.
+// The wait uses ppoll(2) on the timerfd and on an eventfd signaled on
+// cancellation. Like Poll, it releases the P of the calling goroutine
+// while parked.
+func (t *TimerFD) ReadContext(ctx context.Context) (uint64, error) {
+	raw := t.fd.Raw()
+	if raw < 0 {
+		return 0, ErrClosed
+	}
+	var n uint64
+	err := waitContext(ctx, raw, POLLIN, func() (err error) {
+		n, err = t.tryRead(raw)
+		return err
+	})
+	return n, err
+}
+
+// tryRead is Read without waiting, also in blocking mode.
+func (t *TimerFD) tryRead(raw int32) (uint64, error) {
+	var buf [8]byte
+	n, errno := readNow(raw, buf[:], t.blocking)
 	if errno != 0 {
-		if zcall.Errno(errno) == zcall.EAGAIN {
-			return 0, iox.ErrWouldBlock
-		}
-		return 0, errFromErrno(errno)
+		return 0, timerFDReadError(errno)
 	}
 	if n != 8 {
 		return 0, ErrInvalidParam
@@ -172,11 +314,18 @@ func (t *TimerFD) ReadInto(buf []byte) (int, error) {
 	if raw < 0 {
 		return 0, ErrClosed
 	}
-	n, errno := zcall.Read(uintptr(raw), buf[:8])
-	if errno != 0 {
-		return int(n), errFromErrno(errno)
+	return readWait(raw, buf[:8], t.blocking, timerFDReadError)
+}
+
+// timerFDReadError converts a read(2) failure on a timerfd.
+func timerFDReadError(errno uintptr) error {
+	switch zcall.Errno(errno) {
+	case zcall.EAGAIN:
+		return iox.ErrWouldBlock
+	case zcall.ECANCELED:
+		return ErrClockChanged
 	}
-	return int(n), nil
+	return errFromErrno(errno)
 }
 
 // GetTime returns the current timer setting.
@@ -202,6 +351,11 @@ type timespec struct {
 	nsec int64
 }
 
+// nsToTimespec converts nanoseconds to a timespec.
+func nsToTimespec(ns int64) timespec {
+	return timespec{sec: ns / 1e9, nsec: ns % 1e9}
+}
+
 // itimerspec matches struct itimerspec in Linux.
 type itimerspec struct {
 	interval timespec
@@ -210,9 +364,13 @@ type itimerspec struct {
 
 // Clock IDs
 const (
-	CLOCK_REALTIME  = 0
-	CLOCK_MONOTONIC = 1
-	CLOCK_BOOTTIME  = 7
+	CLOCK_REALTIME           = 0
+	CLOCK_MONOTONIC          = 1
+	CLOCK_PROCESS_CPUTIME_ID = 2
+	CLOCK_THREAD_CPUTIME_ID  = 3
+	CLOCK_BOOTTIME           = 7
+	CLOCK_REALTIME_ALARM     = 8
+	CLOCK_BOOTTIME_ALARM     = 9
 )
 
 // timerfd flags
@@ -220,6 +378,8 @@ const (
 	TFD_CLOEXEC       = 0x80000
 	TFD_NONBLOCK      = 0x800
 	TFD_TIMER_ABSTIME = 0x1
+
+	TFD_TIMER_CANCEL_ON_SET = 0x2
 )
 
 // Compile-time interface assertions